@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"protobench/internal/benchmark"
+	"protobench/internal/codec"
+	"protobench/internal/report"
+)
+
+// runCodecBench implements `codec`, which measures serialization alone so
+// that encoding cost can be told apart from transport cost.
+func runCodecBench(args []string) {
+	fs := flag.NewFlagSet("codec", flag.ExitOnError)
+	sizes := sizesFlag{10}
+	fs.Var(&sizes, "kb", "Message size in KB, a list (1,4,16) or a geometric range start:end[:factor]")
+	names := fs.String("codecs", "", "Comma-separated codecs to measure; default all")
+	benchTime := fs.Duration("benchtime", time.Second, "How long to run each operation")
+	format := fs.String("format", "table", "Results format: "+strings.Join(report.Formats, ", "))
+	outPath := fs.String("out", "", "Write results to this file instead of stdout")
+	fs.Parse(args)
+
+	if *format != "table" && *outPath == "" {
+		status = os.Stderr
+	}
+	if *benchTime <= 0 {
+		log.Fatal("-benchtime must be positive")
+	}
+
+	codecs := codec.All()
+	if *names != "" {
+		codecs = nil
+		for _, name := range strings.Split(*names, ",") {
+			c, ok := codec.Lookup(strings.TrimSpace(name))
+			if !ok {
+				var available []string
+				for _, c := range codec.All() {
+					available = append(available, c.Name())
+				}
+				log.Fatalf("Unknown codec %q; available: %s", name, strings.Join(available, ", "))
+			}
+			codecs = append(codecs, c)
+		}
+	}
+
+	fmt.Fprintf(status, "\nMeasuring serialization (%sKB, %s per operation):\n", sizes.String(), *benchTime)
+
+	var results []benchmark.CodecResult
+	for _, size := range sizes {
+		for _, c := range codecs {
+			fmt.Fprintf(status, "  %s %dKB\n", c.Name(), size)
+			result, err := benchmark.BenchmarkCodec(c, size, *benchTime)
+			if err != nil {
+				log.Fatal(err)
+			}
+			results = append(results, result)
+		}
+	}
+
+	rep := report.NewCodecs(benchmark.Options{MessageSize: sizes[0]}, results)
+	if err := writeReport(*format, *outPath, rep); err != nil {
+		log.Fatalf("Failed to write results: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"protobench/internal/report"
+)
+
+// runCompare implements `compare [-threshold pct] old new`. It exits 1 when
+// a regression beyond the threshold is found and 2 on usage errors.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	threshold := fs.Float64("threshold", 5, "Regression threshold in percent for throughput drops and latency increases")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s compare [-threshold pct] <baseline> <candidate>\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	oldPath, newPath := fs.Arg(0), fs.Arg(1)
+
+	oldReport, err := report.Load(oldPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load baseline: %v\n", err)
+		os.Exit(2)
+	}
+	newReport, err := report.Load(newPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load candidate: %v\n", err)
+		os.Exit(2)
+	}
+
+	deltas := report.Compare(oldReport, newReport, *threshold/100)
+	if len(deltas) == 0 {
+		fmt.Fprintln(os.Stderr, "No protocols in common between the two files")
+		os.Exit(2)
+	}
+	report.WriteComparison(os.Stdout, filepath.Base(oldPath), filepath.Base(newPath), deltas)
+
+	regressions := 0
+	for _, d := range deltas {
+		if d.Regression {
+			regressions++
+		}
+	}
+	if regressions > 0 {
+		fmt.Printf("\n%d regression(s) beyond %.1f%%\n", regressions, *threshold)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"protobench/internal/registry"
+)
+
+// warmupFlag accepts either a message count ("500") or a duration ("2s").
+type warmupFlag struct {
+	count    int
+	duration time.Duration
+}
+
+func (w *warmupFlag) String() string {
+	if w.duration > 0 {
+		return w.duration.String()
+	}
+	return strconv.Itoa(w.count)
+}
+
+func (w *warmupFlag) Set(value string) error {
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 {
+			return fmt.Errorf("warmup count must not be negative")
+		}
+		w.count, w.duration = n, 0
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("warmup must be a message count or a duration: %q", value)
+	}
+	w.count, w.duration = 0, d
+	return nil
+}
+
+// sizesFlag accepts a single size ("10"), a list ("1,4,16,64") or a
+// geometric range "start:end[:factor]" such as "1:1024:4", all in KB. The
+// factor defaults to 2.
+type sizesFlag []int
+
+// maxSizeKB bounds a message size at 1 GiB.
+const maxSizeKB = 1 << 20
+
+func (s *sizesFlag) String() string {
+	parts := make([]string, len(*s))
+	for i, kb := range *s {
+		parts[i] = strconv.Itoa(kb)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s *sizesFlag) Set(value string) error {
+	var sizes []int
+	if strings.Contains(value, ":") {
+		parts := strings.Split(value, ":")
+		if len(parts) > 3 {
+			return fmt.Errorf("size range must be start:end[:factor]: %q", value)
+		}
+		nums := []int{0, 0, 2}
+		for i, p := range parts {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return fmt.Errorf("size range must be start:end[:factor]: %q", value)
+			}
+			nums[i] = n
+		}
+		start, end, factor := nums[0], nums[1], nums[2]
+		if start < 1 || end < start || factor < 2 {
+			return fmt.Errorf("size range needs 1 <= start <= end and factor >= 2: %q", value)
+		}
+		if end > maxSizeKB {
+			return fmt.Errorf("message sizes must be at most %d KB: %q", maxSizeKB, value)
+		}
+		for kb := start; ; kb *= factor {
+			sizes = append(sizes, kb)
+			// Stop before the next step could overflow
+			if kb > end/factor {
+				break
+			}
+		}
+	} else {
+		for _, p := range strings.Split(value, ",") {
+			kb, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || kb < 1 {
+				return fmt.Errorf("message sizes must be positive integers: %q", p)
+			}
+			if kb > maxSizeKB {
+				return fmt.Errorf("message sizes must be at most %d KB: %q", maxSizeKB, p)
+			}
+			sizes = append(sizes, kb)
+		}
+	}
+	*s = sizes
+	return nil
+}
+
+// protocolsFlag selects protocols by case-insensitive name or glob pattern.
+// Plain names ("grpc,bson", "protobuf+*") run only those protocols; names
+// prefixed with "-" ("-xml,-json") run everything else. Extra protocols run
+// only when an included name or pattern matches them.
+type protocolsFlag struct {
+	include []string
+	exclude []string
+}
+
+func (p *protocolsFlag) String() string {
+	parts := append([]string(nil), p.include...)
+	for _, name := range p.exclude {
+		parts = append(parts, "-"+name)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (p *protocolsFlag) Set(value string) error {
+	p.include, p.exclude = nil, nil
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "" || name == "-":
+			return fmt.Errorf("empty protocol name in %q", value)
+		case strings.HasPrefix(name, "-"):
+			p.exclude = append(p.exclude, name[1:])
+		default:
+			p.include = append(p.include, name)
+		}
+	}
+	if len(p.include) > 0 && len(p.exclude) > 0 {
+		return fmt.Errorf("cannot mix included and excluded protocols: %q", value)
+	}
+	for _, name := range p.names() {
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("bad protocol pattern %q", name)
+		}
+	}
+	return nil
+}
+
+// names returns every protocol name or pattern the flag mentions.
+func (p *protocolsFlag) names() []string {
+	return append(append([]string(nil), p.include...), p.exclude...)
+}
+
+func (p *protocolsFlag) selects(entry registry.Entry) bool {
+	if matchesAny(p.exclude, entry.Name) {
+		return false
+	}
+	if len(p.include) == 0 {
+		return !entry.Extra
+	}
+	return matchesAny(p.include, entry.Name)
+}
+
+// matchesAny reports whether name equals or matches any of patterns, which
+// are lowercase.
+func matchesAny(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// portsFlag overrides server ports, either for every protocol ("auto") or
+// per protocol ("json=9000,grpc=auto"). "auto" and "0" both pick a free
+// ephemeral port.
+type portsFlag struct {
+	all    string
+	byName map[string]string
+}
+
+func (p *portsFlag) String() string {
+	parts := make([]string, 0, len(p.byName)+1)
+	if p.all != "" {
+		parts = append(parts, p.all)
+	}
+	for name, port := range p.byName {
+		parts = append(parts, name+"="+port)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (p *portsFlag) Set(value string) error {
+	p.all, p.byName = "", make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		name, port, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			name, port = "", name
+		}
+		if port == "auto" {
+			port = "0"
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+			return fmt.Errorf("port must be 0-65535 or auto: %q", part)
+		}
+		if name == "" {
+			p.all = port
+		} else {
+			p.byName[strings.ToLower(name)] = port
+		}
+	}
+	return nil
+}
+
+// names returns every protocol given its own port.
+func (p *portsFlag) names() []string {
+	names := make([]string, 0, len(p.byName))
+	for name := range p.byName {
+		names = append(names, name)
+	}
+	return names
+}
+
+// set reports whether any port was given.
+func (p *portsFlag) set() bool {
+	return p.all != "" || len(p.byName) > 0
+}
+
+// port returns the port to use for a protocol, falling back to def.
+func (p *portsFlag) port(name, def string) string {
+	if port, ok := p.byName[strings.ToLower(name)]; ok {
+		return port
+	}
+	if p.all != "" {
+		return p.all
+	}
+	return def
+}
+
+// settingsFlag collects repeated "protocol.option=value" settings, keyed by
+// lower-cased protocol name.
+type settingsFlag map[string]map[string]string
+
+func (s settingsFlag) String() string {
+	var parts []string
+	for proto, opts := range s {
+		for name, value := range opts {
+			parts = append(parts, proto+"."+name+"="+value)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s settingsFlag) Set(value string) error {
+	key, v, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("setting must be protocol.option=value: %q", value)
+	}
+	proto, name, ok := strings.Cut(key, ".")
+	if !ok || proto == "" || name == "" {
+		return fmt.Errorf("setting must be protocol.option=value: %q", value)
+	}
+	proto = strings.ToLower(proto)
+	if s[proto] == nil {
+		s[proto] = make(map[string]string)
+	}
+	s[proto][name] = v
+	return nil
+}
+
+// names returns every protocol given a setting.
+func (s settingsFlag) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	return names
+}
+
+// addrsFlag collects repeated "protocol=host:port" addresses, keyed by
+// lower-cased protocol name.
+type addrsFlag map[string]string
+
+func (a addrsFlag) String() string {
+	var parts []string
+	for name, addr := range a {
+		parts = append(parts, name+"="+addr)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (a addrsFlag) Set(value string) error {
+	name, addr, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("address must be protocol=host:port: %q", value)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("address must be protocol=host:port: %q", value)
+	}
+	a[strings.ToLower(name)] = addr
+	return nil
+}
+
+// names returns every protocol given an address.
+func (a addrsFlag) names() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"protobench/internal/report"
+)
+
+// runReport implements `report [-out file] [-title text] results...`,
+// rendering exported result files into a single offline HTML page.
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	outPath := fs.String("out", "report.html", "HTML file to write")
+	title := fs.String("title", "Protocol Benchmark Report", "Page title")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s report [-out file] [-title text] <results.json|csv>...\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var sources []report.Source
+	for _, path := range fs.Args() {
+		rep, err := report.Load(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load results: %v\n", err)
+			os.Exit(2)
+		}
+		sources = append(sources, report.Source{Name: filepath.Base(path), Report: rep})
+	}
+
+	f, err := os.Create(*outPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create report: %v\n", err)
+		os.Exit(1)
+	}
+	if err := report.WriteHTML(f, *title, sources); err != nil {
+		f.Close()
+		fmt.Fprintf(os.Stderr, "Failed to render report: %v\n", err)
+		os.Exit(1)
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", *outPath)
+}
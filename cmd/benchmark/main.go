@@ -3,7 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -13,16 +15,58 @@ import (
 
 	"protobench/internal/benchmark"
 	"protobench/internal/model"
-	"protobench/internal/protocols/bson"
-	"protobench/internal/protocols/grpc"
-	"protobench/internal/protocols/json"
-	udp "protobench/internal/protocols/udpack"
-	"protobench/internal/protocols/xml"
+	"protobench/internal/registry"
+	"protobench/internal/remote"
+	"protobench/internal/report"
+
+	// Protocols register themselves with the registry
+	_ "protobench/internal/protocols/bson"
+	_ "protobench/internal/protocols/grpc"
+	_ "protobench/internal/protocols/json"
+	_ "protobench/internal/protocols/layered"
+	_ "protobench/internal/protocols/tcp"
+	_ "protobench/internal/protocols/udpack"
+	_ "protobench/internal/protocols/xml"
 
 	"github.com/schollz/progressbar/v3"
 )
 
-func runProtocolBenchmark(name string, protocol model.Protocol, messageCount int, messageSize int, shouldProfile bool) benchmark.Result {
+// status receives progress bars and notes. It is switched to stderr when
+// machine-readable results go to stdout, keeping that stream parseable.
+var status io.Writer = os.Stdout
+
+// writeReport writes rep in format to path, or to stdout when path is empty.
+func writeReport(format, path string, rep *report.Report) error {
+	if path == "" {
+		return report.Write(os.Stdout, format, rep)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(f, format, rep); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(status, "\nWrote %s results to %s\n", format, path)
+	return nil
+}
+
+// profilePath names a profile file after the label, size and run, so that
+// repeats and sweeps each keep their own.
+func profilePath(label string, run, size int, kind string) string {
+	name := strings.ReplaceAll(label, " ", "_")
+	if sizeSuffix := fmt.Sprintf("_%dKB", size); !strings.HasSuffix(name, sizeSuffix) {
+		name += sizeSuffix
+	}
+	return filepath.Join("profiles", fmt.Sprintf("%s_run%d_%s.prof", name, run, kind))
+}
+
+func runProtocolBenchmark(name string, run int, ledger model.DeliveryTracker, newClient func() model.Client, opts benchmark.Options, shouldProfile bool) benchmark.Result {
 	if shouldProfile {
 		// Create profile directory
 		if err := os.MkdirAll("profiles", 0755); err != nil {
@@ -30,7 +74,7 @@ func runProtocolBenchmark(name string, protocol model.Protocol, messageCount int
 		}
 
 		// CPU Profile
-		cpuFile, err := os.Create(filepath.Join("profiles", fmt.Sprintf("%s_cpu.prof", name)))
+		cpuFile, err := os.Create(profilePath(name, run, opts.MessageSize, "cpu"))
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -42,11 +86,16 @@ func runProtocolBenchmark(name string, protocol model.Protocol, messageCount int
 		defer pprof.StopCPUProfile()
 	}
 
-	runner := benchmark.NewRunner(messageCount, messageSize)
-	runner.AddProtocol(name, protocol)
+	runner := benchmark.NewRunner(opts)
+	runner.AddProtocol(name, ledger, newClient)
 
-	// Create progress bar
-	bar := progressbar.NewOptions(messageCount,
+	// Create progress bar; a timed run has no known total
+	total := opts.MessageCount
+	if opts.Duration > 0 {
+		total = -1
+	}
+	bar := progressbar.NewOptions(total,
+		progressbar.OptionSetWriter(status),
 		progressbar.OptionSetDescription(name),
 		progressbar.OptionEnableColorCodes(false),
 		progressbar.OptionShowCount(),
@@ -61,20 +110,20 @@ func runProtocolBenchmark(name string, protocol model.Protocol, messageCount int
 	)
 
 	// Run benchmark with progress updates
-	results := runner.RunBenchmarkWithProgress(func(sent, errors int) {
+	results := runner.RunBenchmarkWithProgress(func(sent int, errors benchmark.ErrorCounts) {
 		bar.Set(sent)
-		if errors > 0 {
-			bar.Describe(fmt.Sprintf("%s (Errors: %d)", name, errors))
+		if errors.Total() > 0 {
+			bar.Describe(fmt.Sprintf("%s (errors: %s)", name, errors))
 		}
 	})
 
 	bar.Finish()
-	fmt.Println() // Add newline after progress bar
+	fmt.Fprintln(status) // Add newline after progress bar
 
 	if shouldProfile {
 		// Memory Profile
 		runtime.GC()
-		memFile, err := os.Create(filepath.Join("profiles", fmt.Sprintf("%s_mem.prof", name)))
+		memFile, err := os.Create(profilePath(name, run, opts.MessageSize, "mem"))
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -89,52 +138,237 @@ func runProtocolBenchmark(name string, protocol model.Protocol, messageCount int
 }
 
 func main() {
-	shouldProfile := flag.Bool("profile", false, "Enable CPU and memory profiling")
-	messageCount := flag.Int("n", 1000, "Number of messages to send")
-	messageSize := flag.Int("kb", 10, "Size of each message in kilobytes")
-	flag.Parse()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			runBenchmark(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "codec":
+			runCodecBench(os.Args[2:])
+			return
+		case "compare":
+			runCompare(os.Args[2:])
+			return
+		case "report":
+			runReport(os.Args[2:])
+			return
+		case "list-protocols":
+			listProtocols(os.Stdout)
+			return
+		}
+	}
+	runBenchmark(os.Args[1:])
+}
+
+// runBenchmark implements `run`, which is also the default command.
+func runBenchmark(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	shouldProfile := fs.Bool("profile", false, "Enable CPU and memory profiling")
+	messageCount := fs.Int("n", 1000, "Number of messages to send")
+	sizes := sizesFlag{10}
+	fs.Var(&sizes, "kb", "Message size in KB, a list (1,4,16) or a geometric range start:end[:factor] to sweep")
+	concurrency := fs.Int("c", 1, "Number of concurrent client workers, each with its own connection")
+	duration := fs.Duration("duration", 0, "Measure for this long instead of a fixed message count (e.g. 10s)")
+	rate := fs.Float64("rate", 0, "Open-loop mode: schedule this many msgs/sec and measure latency from intended send time")
+	timeout := fs.Duration("timeout", 5*time.Second, "Deadline for each send including its acknowledgement; 0 disables it")
+	runs := fs.Int("runs", 1, "Repeat each protocol benchmark this many times, in random protocol order")
+	format := fs.String("format", "table", "Results format: "+strings.Join(report.Formats, ", "))
+	outPath := fs.String("out", "", "Write results to this file instead of stdout")
+	var warmup warmupFlag
+	fs.Var(&warmup, "warmup", "Discarded warmup before measuring, as a message count (500) or duration (2s)")
+	var selected protocolsFlag
+	fs.Var(&selected, "protocols", "Protocols to run (grpc,bson,protobuf+*) or to skip (-xml,-json); default all but the codec+transport matrix")
+	var ports portsFlag
+	fs.Var(&ports, "ports", "Server ports: auto for ephemeral ports, or per protocol (json=9000,grpc=auto)")
+	connect := make(addrsFlag)
+	fs.Var(connect, "connect", "Benchmark against an already running server instead of starting one, as protocol=host:port (repeatable)")
+	target := fs.String("target", "", "Drive the servers of a `serve` process at host[:control-port] instead of starting them here")
+	stopTarget := fs.Bool("stop-target", false, "Shut down the -target serve process when the benchmark ends")
+	settings := make(settingsFlag)
+	fs.Var(settings, "set", "Protocol option as protocol.option=value, repeatable (see list-protocols)")
+	fs.Parse(args)
+
+	if *target != "" && (len(connect) > 0 || ports.set()) {
+		log.Fatal("-target cannot be combined with -connect or -ports")
+	}
+
+	if *format != "table" && *outPath == "" {
+		status = os.Stderr
+	}
+
+	opts := benchmark.Options{
+		MessageCount:   *messageCount,
+		MessageSize:    sizes[0],
+		Concurrency:    *concurrency,
+		Duration:       *duration,
+		WarmupCount:    warmup.count,
+		WarmupDuration: warmup.duration,
+		Rate:           *rate,
+		Timeout:        *timeout,
+	}
+	if opts.Duration <= 0 && opts.MessageCount <= 0 {
+		log.Fatal("Either -n or -duration must be positive")
+	}
+	if *runs < 1 {
+		log.Fatal("-runs must be at least 1")
+	}
 
 	// Setup protocols
-	clients := []struct {
-		name string
-		port string
-		new  func(string) model.Protocol
-	}{
-		{"JSON", "8080", func(p string) model.Protocol { return json.NewClient(p) }},
-		{"gRPC", "8081", func(p string) model.Protocol { return grpc.NewClient(p) }},
-		{"UDP-ACK", "8082", func(p string) model.Protocol { return udp.NewClient(p) }},
-		{"BSON", "8084", func(p string) model.Protocol { return bson.NewClient(p) }},
-		{"XML", "8085", func(p string) model.Protocol { return xml.NewClient(p) }},
+	checkProtocolPatterns(selected.names())
+	checkProtocolNames(ports.names(), settings.names(), connect.names())
+
+	// With -target the servers run in another process, which reports
+	// their ports and serves their ledgers over its control endpoint
+	var controller *remote.Client
+	remoteServers := make(map[string]remote.ServerInfo)
+	if *target != "" {
+		controller = remote.NewClient(*target)
+		infos, err := controller.Servers()
+		if err != nil {
+			log.Fatalf("Failed to reach %s: %v", *target, err)
+		}
+		for _, info := range infos {
+			remoteServers[strings.ToLower(info.Protocol)] = info
+		}
+		if *stopTarget {
+			defer func() {
+				if err := controller.Shutdown(); err != nil {
+					log.Printf("Failed to stop %s: %v", *target, err)
+				}
+			}()
+		}
+	}
+
+	type protocolClient struct {
+		name   string
+		addr   string                // where workers dial
+		ledger model.DeliveryTracker // nil against an external server
+		new    func(addr string) model.Client
+	}
+	var clients []protocolClient
+	var servers []model.Server
+	defer func() {
+		for _, server := range servers {
+			server.Stop()
+		}
+	}()
+
+	for _, entry := range registry.All() {
+		if !selected.selects(entry) {
+			continue
+		}
+		protoOpts, err := protocolOptions(entry, settings)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c := protocolClient{
+			name: entry.Name,
+			new: func(addr string) model.Client {
+				client, err := entry.NewClient(addr, protoOpts)
+				if err != nil {
+					log.Fatalf("Failed to create %s client: %v", entry.Name, err)
+				}
+				return client
+			},
+		}
+
+		if addr, ok := connect[strings.ToLower(entry.Name)]; ok {
+			c.addr = addr
+			clients = append(clients, c)
+			continue
+		}
+		if controller != nil {
+			info, ok := remoteServers[strings.ToLower(entry.Name)]
+			if !ok {
+				fmt.Fprintf(status, "Skipping %s: not served by %s\n", entry.Name, *target)
+				continue
+			}
+			c.addr = controller.DialAddr(info)
+			c.ledger = controller.Ledger(info.Protocol)
+			clients = append(clients, c)
+			continue
+		}
+
+		// Local servers stay up for the whole sweep so that every size is
+		// measured against the same listeners. Workers dial the address
+		// each server bound, which is only known here when the port was
+		// auto-assigned.
+		port := ports.port(entry.Name, entry.DefaultPort)
+		server, err := entry.NewServer(":"+port, protoOpts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := server.Start(); err != nil {
+			log.Fatalf("Failed to start %s on port %s: %v", entry.Name, port, err)
+		}
+		servers = append(servers, server)
+		c.addr = server.Addr()
+		c.ledger = server.Ledger()
+		clients = append(clients, c)
+	}
+	if len(clients) == 0 {
+		log.Fatal("No protocols to benchmark")
 	}
 
 	var results []benchmark.Result
 
-	fmt.Printf("\nRunning benchmarks (%d messages, %dKB each):\n\n", *messageCount, *messageSize)
+	workload := fmt.Sprintf("%d messages", *messageCount)
+	if opts.Duration > 0 {
+		workload = opts.Duration.String()
+	}
+	if opts.Rate > 0 {
+		workload += fmt.Sprintf(" at %.0f msgs/sec", opts.Rate)
+	}
+	fmt.Fprintf(status, "\nRunning benchmarks (%s, %sKB each, %d workers, warmup %s):\n\n", workload, sizes.String(), *concurrency, warmup.String())
 
-	for _, c := range clients {
-		client := c.new(c.port)
-		if err := client.StartServer(); err != nil {
-			log.Fatalf("Failed to start %s server: %v", c.name, err)
+	for run := 1; run <= *runs; run++ {
+		if *runs > 1 {
+			fmt.Fprintf(status, "Run %d/%d:\n", run, *runs)
 		}
 
-		result := runProtocolBenchmark(c.name, client, *messageCount, *messageSize, *shouldProfile)
-		results = append(results, result)
+		for _, size := range sizes {
+			// Shuffle so that drift on the host (thermal, background load)
+			// does not consistently favour whichever protocol runs first
+			if *runs > 1 {
+				rand.Shuffle(len(clients), func(i, j int) { clients[i], clients[j] = clients[j], clients[i] })
+			}
+
+			sizeOpts := opts
+			sizeOpts.MessageSize = size
+			for _, c := range clients {
+				label := c.name
+				if len(sizes) > 1 {
+					label = fmt.Sprintf("%s %dKB", c.name, size)
+				}
 
-		client.StopServer()
+				newClient := func() model.Client { return c.new(c.addr) }
+				result := runProtocolBenchmark(label, run, c.ledger, newClient, sizeOpts, *shouldProfile)
+				result.Protocol = c.name
+				result.Run = run
+				results = append(results, result)
+			}
+		}
 	}
 
-	// Print final results table
-	fmt.Println("\nResults:")
-	fmt.Printf("%-12s %12s %15s %10s %10s\n", "Protocol", "Time", "Msgs/sec", "Errors", "Missing")
-	fmt.Println(strings.Repeat("-", 65))
+	rep := report.New(opts, *runs, results)
+	if err := writeReport(*format, *outPath, rep); err != nil {
+		log.Fatalf("Failed to write results: %v", err)
+	}
 
+	// An open-loop run that fell behind its schedule reports queueing, not
+	// service time; make that visible.
 	for _, result := range results {
-		fmt.Printf("%-12s %12s %15.2f %10d %10d\n",
-			result.Protocol,
-			result.TotalTime.Round(time.Millisecond),
-			result.MessagesPerSecond,
-			result.Errors,
-			result.Missing,
-		)
+		if result.TargetRate > 0 && result.MessagesPerSecond < 0.95*result.TargetRate {
+			fmt.Fprintf(status, "\nNote: %s achieved %.2f msgs/sec against a target of %.0f; latencies include queueing delay. Consider raising -c.\n",
+				result.Protocol, result.MessagesPerSecond, result.TargetRate)
+		}
+	}
+
+	if *runs > 1 {
+		printSummary(status, results)
 	}
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"protobench/internal/registry"
+)
+
+// listProtocols implements `list-protocols`.
+func listProtocols(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPORT\tCAPABILITIES\tDESCRIPTION")
+	extra := false
+	for _, e := range registry.All() {
+		name := e.Name
+		if e.Extra {
+			name += " *"
+			extra = true
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, e.DefaultPort, e.Capabilities, e.Description)
+		for _, o := range e.Options {
+			fmt.Fprintf(tw, "\t\t\t  -set %s.%s=%s  (%s)\n", e.Name, o.Name, o.Default, o.Usage)
+		}
+	}
+	tw.Flush()
+	if extra {
+		fmt.Fprintln(w, "\n* runs only when selected with -protocols, e.g. -protocols 'protobuf+*'")
+	}
+}
+
+// checkProtocolNames exits if any flag names a protocol that is not
+// registered.
+func checkProtocolNames(lists ...[]string) {
+	for _, names := range lists {
+		for _, name := range names {
+			if _, ok := registry.Lookup(name); !ok {
+				log.Fatalf("Unknown protocol %q; available: %s", name, strings.Join(registry.Names(), ", "))
+			}
+		}
+	}
+}
+
+// checkProtocolPatterns exits if any name or pattern matches no registered
+// protocol.
+func checkProtocolPatterns(patterns []string) {
+	for _, pattern := range patterns {
+		matched := false
+		for _, name := range registry.Names() {
+			if matchesAny([]string{pattern}, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			log.Fatalf("No protocol matches %q; available: %s", pattern, strings.Join(registry.Names(), ", "))
+		}
+	}
+}
+
+// protocolOptions resolves the -set options for entry. It builds one client
+// and server up front so that bad option values fail here rather than
+// inside a worker.
+func protocolOptions(entry registry.Entry, settings settingsFlag) (registry.Options, error) {
+	opts, err := entry.Resolve(settings[strings.ToLower(entry.Name)])
+	if err != nil {
+		return nil, err
+	}
+	client, err := entry.NewClient("", opts)
+	if err != nil {
+		return nil, err
+	}
+	client.Close()
+	if _, err := entry.NewServer("", opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"protobench/internal/model"
+	"protobench/internal/registry"
+	"protobench/internal/remote"
+)
+
+// runServe implements `serve`, which starts protocol servers for a `run
+// -target` process on another host to benchmark against.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	control := fs.String("control", ":"+remote.DefaultControlPort, "Listen address of the control endpoint")
+	var selected protocolsFlag
+	fs.Var(&selected, "protocols", "Protocols to serve (grpc,bson,protobuf+*) or to skip (-xml,-json); default all but the codec+transport matrix")
+	var ports portsFlag
+	fs.Var(&ports, "ports", "Server ports: auto for ephemeral ports, or per protocol (json=9000,grpc=auto)")
+	settings := make(settingsFlag)
+	fs.Var(settings, "set", "Protocol option as protocol.option=value, repeatable (see list-protocols)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s serve [-control addr] [-protocols list] [-ports list] [-set opt]...\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	checkProtocolPatterns(selected.names())
+	checkProtocolNames(ports.names(), settings.names())
+
+	controller := remote.NewController()
+	var servers []model.Server
+	defer func() {
+		for _, server := range servers {
+			server.Stop()
+		}
+	}()
+
+	for _, entry := range registry.All() {
+		if !selected.selects(entry) {
+			continue
+		}
+		opts, err := protocolOptions(entry, settings)
+		if err != nil {
+			log.Fatal(err)
+		}
+		port := ports.port(entry.Name, entry.DefaultPort)
+		server, err := entry.NewServer(":"+port, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := server.Start(); err != nil {
+			log.Fatalf("Failed to start %s on port %s: %v", entry.Name, port, err)
+		}
+		servers = append(servers, server)
+		controller.Add(entry.Name, server)
+
+		_, bound, _ := net.SplitHostPort(server.Addr())
+		fmt.Printf("Serving %s on port %s\n", entry.Name, bound)
+	}
+	if len(servers) == 0 {
+		log.Fatal("No protocols to serve")
+	}
+
+	if err := controller.Start(*control); err != nil {
+		log.Fatalf("Failed to start control endpoint: %v", err)
+	}
+	defer controller.Stop()
+	fmt.Printf("Control endpoint on %s; stop with Ctrl-C or `run -target ... -stop-target`\n", *control)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-signals:
+	case <-controller.Done():
+	}
+	fmt.Println("Shutting down")
+}
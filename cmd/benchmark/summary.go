@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"protobench/internal/benchmark"
+)
+
+// printSummary reports the mean and 95% confidence interval of each metric
+// across repeated runs, then tests adjacent protocols in each ranking.
+func printSummary(w io.Writer, results []benchmark.Result) {
+	summaries := benchmark.Summarize(results)
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].MessageSize != summaries[j].MessageSize {
+			return summaries[i].MessageSize < summaries[j].MessageSize
+		}
+		return summaries[i].Throughput.Mean > summaries[j].Throughput.Mean
+	})
+
+	fmt.Fprintln(w, "\nSummary (mean ± 95% CI):")
+	fmt.Fprintf(w, "%-14s %6s %5s %22s %22s %22s %22s %22s\n",
+		"Protocol", "Size", "Runs", "Msgs/sec", "P50", "P90", "P99", "P99.9")
+	fmt.Fprintln(w, strings.Repeat("-", 142))
+
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%-14s %6s %5d %22s %22s %22s %22s %22s\n",
+			s.Protocol,
+			fmt.Sprintf("%dKB", s.MessageSize),
+			s.Runs,
+			fmt.Sprintf("%.2f ± %.2f", s.Throughput.Mean, s.Throughput.Margin()),
+			formatLatencySample(s.P50),
+			formatLatencySample(s.P90),
+			formatLatencySample(s.P99),
+			formatLatencySample(s.P999),
+		)
+	}
+
+	fmt.Fprintf(w, "\nComparisons (Welch's t-test, p < %.2f):\n", benchmark.Significance)
+	for _, c := range benchmark.CompareAdjacent(summaries) {
+		verdict := fmt.Sprintf("p=%.4f", c.PValue)
+		if !c.Significant {
+			verdict += ", not significant"
+		}
+		if math.IsNaN(c.PValue) {
+			verdict = "too few runs to test"
+		}
+		fmt.Fprintf(w, "  %6s  %-14s beats %-14s by %6.1f%% on %s (%s)\n",
+			fmt.Sprintf("%dKB", c.MessageSize), c.Faster, c.Slower, c.Delta*100, c.Metric, verdict)
+	}
+}
+
+func formatLatencySample(s benchmark.Sample) string {
+	return fmt.Sprintf("%s ± %s",
+		time.Duration(s.Mean).Round(time.Microsecond),
+		time.Duration(s.Margin()).Round(time.Microsecond))
+}
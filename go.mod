@@ -5,6 +5,8 @@ go 1.22
 toolchain go1.22.4
 
 require (
+	github.com/schollz/progressbar/v3 v3.18.0
+	go.mongodb.org/mongo-driver v1.17.2
 	google.golang.org/grpc v1.70.0
 	google.golang.org/protobuf v1.36.4
 )
@@ -12,8 +14,6 @@ require (
 require (
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
-	github.com/schollz/progressbar/v3 v3.18.0 // indirect
-	go.mongodb.org/mongo-driver v1.17.2 // indirect
 	golang.org/x/net v0.32.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/term v0.28.0 // indirect
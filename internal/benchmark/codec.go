@@ -0,0 +1,108 @@
+package benchmark
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"protobench/internal/codec"
+	"protobench/internal/model"
+)
+
+// OpStats is the cost of one codec operation, averaged over Iterations.
+type OpStats struct {
+	Iterations  int     `json:"iterations"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+}
+
+// maxOpIterations bounds the loop for operations too fast to time.
+const maxOpIterations = 1_000_000_000
+
+// measureOp runs op in growing batches, the way testing.B sizes its loop,
+// until one batch lasts benchTime, and reports that batch. Allocations are
+// read from the runtime's counters around it.
+func measureOp(op func() error, benchTime time.Duration) (OpStats, error) {
+	n := 1
+	for {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			if err := op(); err != nil {
+				return OpStats{}, err
+			}
+		}
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		if elapsed >= benchTime || n >= maxOpIterations {
+			return OpStats{
+				Iterations:  n,
+				NsPerOp:     float64(elapsed.Nanoseconds()) / float64(n),
+				AllocsPerOp: int64((after.Mallocs - before.Mallocs) / uint64(n)),
+				BytesPerOp:  int64((after.TotalAlloc - before.TotalAlloc) / uint64(n)),
+			}, nil
+		}
+
+		// Aim a little past benchTime, growing at most a hundredfold
+		next := n * 100
+		if elapsed > 0 {
+			next = int(1.2 * float64(n) * float64(benchTime) / float64(elapsed))
+		}
+		n = max(min(next, n*100, maxOpIterations), n+1)
+	}
+}
+
+// CodecResult is one codec's serialization cost for one message size, with
+// no transport involved.
+type CodecResult struct {
+	Codec        string  `json:"codec"`
+	MessageSize  int     `json:"message_size_kb"`
+	EncodedBytes int     `json:"encoded_bytes"`
+	Marshal      OpStats `json:"marshal"`
+	Unmarshal    OpStats `json:"unmarshal"`
+}
+
+// BenchmarkCodec measures marshalling and unmarshalling the benchmark's test
+// message of sizeKB with c, running each operation for about benchTime. It
+// fails if the message does not survive the round trip.
+func BenchmarkCodec(c codec.Codec, sizeKB int, benchTime time.Duration) (CodecResult, error) {
+	msg := generateTestMessage(1, sizeKB)
+	data, err := c.Marshal(msg)
+	if err != nil {
+		return CodecResult{}, fmt.Errorf("%s: failed to marshal: %w", c.Name(), err)
+	}
+	var decoded model.Message
+	if err := c.Unmarshal(data, &decoded); err != nil {
+		return CodecResult{}, fmt.Errorf("%s: failed to unmarshal: %w", c.Name(), err)
+	}
+	if decoded.Number != msg.Number || !decoded.Verify() {
+		return CodecResult{}, fmt.Errorf("%s: message changed in round trip", c.Name())
+	}
+
+	marshal, err := measureOp(func() error {
+		_, err := c.Marshal(msg)
+		return err
+	}, benchTime)
+	if err != nil {
+		return CodecResult{}, fmt.Errorf("%s: failed to marshal: %w", c.Name(), err)
+	}
+	unmarshal, err := measureOp(func() error {
+		var m model.Message
+		return c.Unmarshal(data, &m)
+	}, benchTime)
+	if err != nil {
+		return CodecResult{}, fmt.Errorf("%s: failed to unmarshal: %w", c.Name(), err)
+	}
+
+	return CodecResult{
+		Codec:        c.Name(),
+		MessageSize:  sizeKB,
+		EncodedBytes: len(data),
+		Marshal:      marshal,
+		Unmarshal:    unmarshal,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"protobench/internal/model"
+)
+
+// samplesPerKind bounds how many distinct messages are kept per error kind.
+const samplesPerKind = 3
+
+// ErrorCounts holds a count per error kind.
+type ErrorCounts [model.NumErrorKinds]int
+
+func (c ErrorCounts) Total() int {
+	total := 0
+	for _, n := range c {
+		total += n
+	}
+	return total
+}
+
+// String lists the non-zero counts, most frequent first, e.g.
+// "timeout 12, dial 1".
+func (c ErrorCounts) String() string {
+	kinds := make([]model.ErrorKind, 0, len(c))
+	for k, n := range c {
+		if n > 0 {
+			kinds = append(kinds, model.ErrorKind(k))
+		}
+	}
+	sort.SliceStable(kinds, func(i, j int) bool { return c[kinds[i]] > c[kinds[j]] })
+
+	parts := make([]string, len(kinds))
+	for i, k := range kinds {
+		parts[i] = fmt.Sprintf("%s %d", k, c[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ErrorBucket counts the failed sends of one kind, with a few example
+// messages.
+type ErrorBucket struct {
+	Kind    string   `json:"kind"`
+	Count   int      `json:"count"`
+	Samples []string `json:"samples,omitempty"`
+}
+
+// errorTally classifies errors as workers report them. Counting is
+// lock-free; only new sample messages take the lock.
+type errorTally struct {
+	counts  [model.NumErrorKinds]atomic.Int64
+	mu      sync.Mutex
+	samples [model.NumErrorKinds][]string
+}
+
+func (t *errorTally) add(err error) {
+	kind := model.Classify(err)
+	if t.counts[kind].Add(1) > 1000 {
+		// Distinct messages are long since collected, if there are any
+		return
+	}
+
+	msg := err.Error()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples[kind]) >= samplesPerKind {
+		return
+	}
+	for _, s := range t.samples[kind] {
+		if s == msg {
+			return
+		}
+	}
+	t.samples[kind] = append(t.samples[kind], msg)
+}
+
+func (t *errorTally) snapshot() ErrorCounts {
+	var c ErrorCounts
+	for k := range t.counts {
+		c[k] = int(t.counts[k].Load())
+	}
+	return c
+}
+
+// buckets returns the non-empty kinds, most frequent first.
+func (t *errorTally) buckets() []ErrorBucket {
+	counts := t.snapshot()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var buckets []ErrorBucket
+	for k, n := range counts {
+		if n > 0 {
+			buckets = append(buckets, ErrorBucket{
+				Kind:    model.ErrorKind(k).String(),
+				Count:   n,
+				Samples: t.samples[k],
+			})
+		}
+	}
+	sort.SliceStable(buckets, func(i, j int) bool { return buckets[i].Count > buckets[j].Count })
+	return buckets
+}
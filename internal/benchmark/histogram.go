@@ -0,0 +1,214 @@
+package benchmark
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Each power-of-two range of values is split into subBucketHalf linear
+// sub-buckets, which bounds the relative error of any percentile to
+// 1/subBucketHalf, about 1.6%.
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+)
+
+// Histogram records durations into log-linear buckets in the style of
+// HdrHistogram. Min, max, mean and standard deviation are tracked exactly;
+// percentiles are resolved to the bucket containing them.
+type Histogram struct {
+	counts []int64
+	total  int64
+	min    int64
+	max    int64
+	sum    float64
+	sumSq  float64
+}
+
+// LatencyStats summarises a latency distribution.
+type LatencyStats struct {
+	Min    time.Duration `json:"min_ns"`
+	Mean   time.Duration `json:"mean_ns"`
+	P50    time.Duration `json:"p50_ns"`
+	P90    time.Duration `json:"p90_ns"`
+	P99    time.Duration `json:"p99_ns"`
+	P999   time.Duration `json:"p999_ns"`
+	Max    time.Duration `json:"max_ns"`
+	StdDev time.Duration `json:"stddev_ns"`
+}
+
+// Quantile is one point of a latency CDF.
+type Quantile struct {
+	Percentile float64       `json:"percentile"`
+	Value      time.Duration `json:"value_ns"`
+}
+
+// cdfPercentiles are dense in the tail, where protocols tend to differ most.
+var cdfPercentiles = []float64{
+	0, 1, 5, 10, 20, 30, 40, 50, 60, 70, 80, 90,
+	95, 97.5, 99, 99.5, 99.9, 99.95, 99.99, 100,
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{
+		counts: make([]int64, subBucketCount),
+		min:    math.MaxInt64,
+	}
+}
+
+func bucketIndex(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	return shift*subBucketHalf + int(v>>shift)
+}
+
+// bucketUpperBound returns the highest value that maps to bucket idx.
+func bucketUpperBound(idx int) int64 {
+	if idx < subBucketCount {
+		return int64(idx)
+	}
+	shift := idx/subBucketHalf - 1
+	sub := int64(idx - shift*subBucketHalf)
+	return (sub+1)<<shift - 1
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d)
+	if v < 0 {
+		v = 0
+	}
+
+	idx := bucketIndex(v)
+	if idx >= len(h.counts) {
+		grown := make([]int64, idx+1)
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	h.counts[idx]++
+
+	h.total++
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	f := float64(v)
+	h.sum += f
+	h.sumSq += f * f
+}
+
+// Merge adds all samples recorded in other to h.
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.total == 0 {
+		return
+	}
+	if len(other.counts) > len(h.counts) {
+		grown := make([]int64, len(other.counts))
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+
+	h.total += other.total
+	if other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.sum += other.sum
+	h.sumSq += other.sumSq
+}
+
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+// Percentile returns the value below which q percent of samples fall.
+func (h *Histogram) Percentile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return time.Duration(h.min)
+	}
+	if q >= 100 {
+		return time.Duration(h.max)
+	}
+
+	rank := int64(math.Ceil(q / 100 * float64(h.total)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			v := bucketUpperBound(i)
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return time.Duration(v)
+		}
+	}
+	return time.Duration(h.max)
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.total))
+}
+
+func (h *Histogram) StdDev() time.Duration {
+	if h.total < 2 {
+		return 0
+	}
+	n := float64(h.total)
+	mean := h.sum / n
+	variance := (h.sumSq - n*mean*mean) / (n - 1)
+	if variance < 0 {
+		variance = 0
+	}
+	return time.Duration(math.Sqrt(variance))
+}
+
+// CDF samples the distribution at fixed percentiles for charting.
+func (h *Histogram) CDF() []Quantile {
+	if h.total == 0 {
+		return nil
+	}
+	cdf := make([]Quantile, len(cdfPercentiles))
+	for i, p := range cdfPercentiles {
+		cdf[i] = Quantile{Percentile: p, Value: h.Percentile(p)}
+	}
+	return cdf
+}
+
+func (h *Histogram) Stats() LatencyStats {
+	if h.total == 0 {
+		return LatencyStats{}
+	}
+	return LatencyStats{
+		Min:    time.Duration(h.min),
+		Mean:   h.Mean(),
+		P50:    h.Percentile(50),
+		P90:    h.Percentile(90),
+		P99:    h.Percentile(99),
+		P999:   h.Percentile(99.9),
+		Max:    time.Duration(h.max),
+		StdDev: h.StdDev(),
+	}
+}
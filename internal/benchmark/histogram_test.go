@@ -0,0 +1,33 @@
+package benchmark
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBucketRoundTrip(t *testing.T) {
+	maxErr := 1.0 / subBucketHalf
+	values := []int64{0, 1, subBucketCount - 1, subBucketCount, subBucketCount + 1, 1000, 12345, 999_999, 1 << 40, math.MaxInt64 >> 2}
+	for v := int64(1); v < 1<<40; v = v*3/2 + 1 {
+		values = append(values, v)
+	}
+
+	for _, v := range values {
+		idx := bucketIndex(v)
+		upper := bucketUpperBound(idx)
+		if upper < v {
+			t.Errorf("value %d: upper bound %d of bucket %d is below it", v, upper, idx)
+		}
+		if bucketIndex(upper) != idx {
+			t.Errorf("value %d: upper bound %d maps to bucket %d, not %d", v, upper, bucketIndex(upper), idx)
+		}
+		if v > 0 {
+			if rel := float64(upper-v) / float64(v); rel > maxErr {
+				t.Errorf("value %d: relative error %.4f exceeds %.4f", v, rel, maxErr)
+			}
+		}
+		if idx > 0 && bucketUpperBound(idx-1) >= v {
+			t.Errorf("value %d: previous bucket %d also covers it", v, idx-1)
+		}
+	}
+}
@@ -1,11 +1,43 @@
 package benchmark
 
-import "time"
+import (
+	"time"
 
+	"protobench/internal/model"
+)
+
+// Result is one protocol's outcome for one run. The JSON field names form
+// part of the exported file schema; durations are in nanoseconds.
 type Result struct {
-	Protocol          string
-	TotalTime         time.Duration
-	MessagesPerSecond float64
-	Errors            int
-	Missing           int
+	Protocol          string        `json:"protocol"`
+	Run               int           `json:"run"` // 1-based trial number when repeating runs
+	MessageSize       int           `json:"message_size_kb"`
+	TotalTime         time.Duration `json:"total_time_ns"`
+	Messages          int           `json:"messages"`
+	MessagesPerSecond float64       `json:"messages_per_second"`
+	TargetRate        float64       `json:"target_rate"` // open-loop schedule; zero for closed-loop runs
+	Errors            int           `json:"errors"`      // failed sends other than timeouts
+	Timeouts          int           `json:"timeouts"`    // sends that got no response or ack in time
+	ErrorBreakdown    []ErrorBucket `json:"error_breakdown,omitempty"`
+	Missing           int           `json:"missing"` // sent but never seen by the server
+	Duplicates        int           `json:"duplicates"`
+	OutOfOrder        int           `json:"out_of_order"`
+	Corrupted         int           `json:"corrupted"`
+	Partial           int           `json:"partial"`   // still being reassembled by the server when the run ended
+	Abandoned         int           `json:"abandoned"` // given up on by the server after arriving only in part
+	Latency           LatencyStats  `json:"latency"`
+	LatencyCDF        []Quantile    `json:"latency_cdf,omitempty"`
+
+	// Traffic as counted on the server's sockets, so framing, headers and
+	// acknowledgements are included. Zero without a server ledger.
+	BytesSent       int64   `json:"bytes_sent"`        // client to server
+	BytesReceived   int64   `json:"bytes_received"`    // server to client
+	BytesPerMessage float64 `json:"bytes_per_message"` // both directions
+	OverheadRatio   float64 `json:"overhead_ratio"`    // wire bytes over message content bytes
+	WireReads       int64   `json:"wire_reads"`        // server read calls; packets for UDP
+	WireWrites      int64   `json:"wire_writes"`       // server write calls; packets for UDP
+
+	// ProtocolStats holds the internals a protocol's clients report, such
+	// as retransmissions; nil for protocols that report none.
+	ProtocolStats *model.ProtocolStats `json:"protocol_stats,omitempty"`
 }
@@ -1,30 +1,67 @@
 package benchmark
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"protobench/internal/model"
 )
 
+// Options controls how a Runner drives each protocol.
+type Options struct {
+	MessageCount int `json:"message_count"`
+	MessageSize  int `json:"message_size_kb"`
+	Concurrency  int `json:"concurrency"` // number of workers, each with its own client
+
+	// Duration, when set, measures for a fixed wall-clock window instead
+	// of a fixed MessageCount.
+	Duration time.Duration `json:"duration_ns"`
+
+	// A warmup phase runs before measurement and is discarded. It lasts
+	// for WarmupCount messages or WarmupDuration, whichever is set.
+	WarmupCount    int           `json:"warmup_count"`
+	WarmupDuration time.Duration `json:"warmup_duration_ns"`
+
+	// Rate, when positive, switches to open-loop load: messages are
+	// scheduled at Rate per second regardless of how fast earlier sends
+	// complete, and latency is measured from each message's intended send
+	// time so that queueing delay is not hidden (coordinated omission).
+	Rate float64 `json:"rate"`
+
+	// Timeout bounds each send, including any acknowledgement. Zero means
+	// no limit.
+	Timeout time.Duration `json:"timeout_ns"`
+}
+
 type Runner struct {
-	messageCount int
-	messageSize  int // in KB
-	clients      map[string]model.Protocol
+	opts    Options
+	clients map[string]func() model.Client
+	ledgers map[string]model.DeliveryTracker
 }
 
-func NewRunner(messageCount, messageSize int) *Runner {
+func NewRunner(opts Options) *Runner {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
 	return &Runner{
-		messageCount: messageCount,
-		messageSize:  messageSize,
-		clients:      make(map[string]model.Protocol),
+		opts:    opts,
+		clients: make(map[string]func() model.Client),
+		ledgers: make(map[string]model.DeliveryTracker),
 	}
 }
 
-func (r *Runner) AddProtocol(name string, protocol model.Protocol) {
-	r.clients[name] = protocol
+// AddProtocol registers a protocol under name. newClient is called once per
+// worker so that every worker sends over its own connection; the server is
+// expected to be running already and to record into ledger, which the
+// Runner consults to count what actually arrived. A nil ledger falls back
+// to the client's own error count.
+func (r *Runner) AddProtocol(name string, ledger model.DeliveryTracker, newClient func() model.Client) {
+	r.clients[name] = newClient
+	r.ledgers[name] = ledger
 }
 
 func generateTestMessage(id, sizeKB int) *model.Message {
@@ -41,141 +78,279 @@ func generateTestMessage(id, sizeKB int) *model.Message {
 			i, i, i*2, i*3, i*4))
 	}
 
-	return &model.Message{
+	msg := &model.Message{
 		ID:        fmt.Sprintf("msg-%d", id),
 		Timestamp: time.Now(),
 		Content:   strings.Join(content, "\n"),
 		Number:    int64(id),
 		IsValid:   true,
 	}
+	msg.Checksum = model.ContentChecksum(msg.Content)
+	return msg
 }
 
 func (r *Runner) RunBenchmark() []Result {
+	return r.RunBenchmarkWithProgress(nil)
+}
+
+func (r *Runner) RunBenchmarkWithProgress(progressFn func(sent int, errors ErrorCounts)) []Result {
 	var results []Result
 
-	for name, protocol := range r.clients {
-		start := time.Now()
-		errors := 0
-		received := make(map[int]bool)
-
-		for i := 0; i < r.messageCount; i++ {
-			msg := generateTestMessage(i, r.messageSize)
-			if err := protocol.SendMessage(msg); err != nil {
-				errors++
-			} else {
-				received[i] = true
-			}
+	for name, newClient := range r.clients {
+		results = append(results, r.benchmarkProtocol(name, newClient, r.ledgers[name], progressFn))
+	}
+	return results
+}
+
+// phase bounds one stretch of sending by message count, wall-clock
+// duration, or both. A zero bound is ignored. A positive rate paces sends
+// on a fixed timetable.
+type phase struct {
+	firstID  int
+	count    int
+	duration time.Duration
+	rate     float64
+}
+
+// intendedStart returns when message n of a paced phase is due.
+func (p phase) intendedStart(start time.Time, n int) time.Time {
+	return start.Add(time.Duration(float64(n) * float64(time.Second) / p.rate))
+}
+
+type phaseResult struct {
+	sent    int
+	content int64 // bytes of message content attempted
+	errors  ErrorCounts
+	buckets []ErrorBucket
+	elapsed time.Duration
+	latency *Histogram
+}
+
+func (r *Runner) benchmarkProtocol(name string, newClient func() model.Client, ledger model.DeliveryTracker, progressFn func(sent int, errors ErrorCounts)) Result {
+	clients := make([]model.Client, r.opts.Concurrency)
+	for i := range clients {
+		clients[i] = newClient()
+	}
+	defer func() {
+		for _, client := range clients {
+			client.Close()
 		}
+	}()
 
-		duration := time.Since(start)
-		messagesPerSecond := float64(r.messageCount) / duration.Seconds()
+	// Warmup establishes connections and grows buffers; its numbers are dropped
+	nextID := 0
+	if r.opts.WarmupCount > 0 || r.opts.WarmupDuration > 0 {
+		warmup := r.runPhase(clients, phase{
+			count:    r.opts.WarmupCount,
+			duration: r.opts.WarmupDuration,
+			rate:     r.opts.Rate,
+		}, nil)
+		nextID = warmup.sent
+	}
 
-		// Check for missing messages
-		missing := 0
-		for i := 0; i < r.messageCount; i++ {
-			if !received[i] {
-				missing++
+	measure := phase{firstID: nextID, count: r.opts.MessageCount, rate: r.opts.Rate}
+	if r.opts.Duration > 0 {
+		measure.count = 0
+		measure.duration = r.opts.Duration
+	}
+	// A ledger that cannot be reset would count warmup messages, so the
+	// run falls back to client errors instead
+	if ledger != nil && ledger.ResetDelivery() != nil {
+		ledger = nil
+	}
+	collectStats(clients) // drop what warmup accumulated
+	measured := r.runPhase(clients, measure, progressFn)
+	stats := collectStats(clients)
+
+	result := Result{
+		Protocol:          name,
+		MessageSize:       r.opts.MessageSize,
+		TotalTime:         measured.elapsed,
+		Messages:          measured.sent,
+		MessagesPerSecond: float64(measured.sent) / measured.elapsed.Seconds(),
+		TargetRate:        r.opts.Rate,
+		Errors:            measured.errors.Total() - measured.errors[model.ErrTimeout],
+		Timeouts:          measured.errors[model.ErrTimeout],
+		ErrorBreakdown:    measured.buckets,
+		Missing:           measured.errors.Total(),
+		Latency:           measured.latency.Stats(),
+		LatencyCDF:        measured.latency.CDF(),
+		ProtocolStats:     stats,
+	}
+
+	// Without a server ledger, unacknowledged messages are assumed missing
+	if ledger != nil {
+		delivery, err := ledger.Delivery()
+		if err != nil {
+			return result
+		}
+		result.Missing = max(measured.sent-delivery.Unique, 0)
+		result.Duplicates = delivery.Duplicates
+		result.OutOfOrder = delivery.OutOfOrder
+		result.Corrupted = delivery.Corrupted
+		result.Partial = delivery.Partial
+		result.Abandoned = delivery.Abandoned
+		if len(delivery.Counters) > 0 {
+			if result.ProtocolStats == nil {
+				result.ProtocolStats = &model.ProtocolStats{}
 			}
+			result.ProtocolStats.Counters = mergeCounters(result.ProtocolStats.Counters, delivery.Counters)
+		}
+
+		traffic := delivery.Traffic
+		result.BytesSent = traffic.BytesRead
+		result.BytesReceived = traffic.BytesWritten
+		result.WireReads = traffic.Reads
+		result.WireWrites = traffic.Writes
+		if wireBytes := float64(traffic.BytesRead + traffic.BytesWritten); measured.sent > 0 && measured.content > 0 {
+			result.BytesPerMessage = wireBytes / float64(measured.sent)
+			result.OverheadRatio = wireBytes / float64(measured.content)
 		}
+	}
+	return result
+}
 
-		results = append(results, Result{
-			Protocol:          name,
-			TotalTime:         duration,
-			MessagesPerSecond: messagesPerSecond,
-			Errors:            errors,
-			Missing:           missing,
-		})
+// collectStats merges the protocol statistics of clients that keep them, or
+// returns nil when none do.
+func collectStats(clients []model.Client) *model.ProtocolStats {
+	var all []model.ProtocolStats
+	for _, client := range clients {
+		if reporter, ok := client.(model.StatsReporter); ok {
+			all = append(all, reporter.Stats())
+		}
 	}
+	if len(all) == 0 {
+		return nil
+	}
+	merged := model.MergeStats(all)
+	return &merged
+}
 
-	return results
+// mergeCounters adds the server's counters to the clients'.
+func mergeCounters(dst, src map[string]int64) map[string]int64 {
+	if dst == nil {
+		dst = make(map[string]int64, len(src))
+	}
+	for name, v := range src {
+		dst[name] += v
+	}
+	return dst
 }
 
-func (r *Runner) benchmarkProtocol(name string, protocol model.Protocol) Result {
-	var wg sync.WaitGroup
-	errorCount := 0
-	var errorCountMutex sync.Mutex
+// progressInterval is how often a running phase reports its progress.
+const progressInterval = 100 * time.Millisecond
 
-	start := time.Now()
+func (r *Runner) runPhase(clients []model.Client, p phase, progressFn func(sent int, errors ErrorCounts)) phaseResult {
+	var (
+		next     atomic.Int64
+		sent     atomic.Int64
+		content  atomic.Int64
+		failures errorTally
+		wg       sync.WaitGroup
+	)
+	histograms := make([]*Histogram, len(clients))
 
-	// Create channel for concurrent message sending
-	msgChan := make(chan struct{}, r.messageCount)
+	start := time.Now()
+	var deadline time.Time
+	if p.duration > 0 {
+		deadline = start.Add(p.duration)
+	}
 
-	// Launch workers
-	for i := 0; i < r.messageCount; i++ {
+	for w, client := range clients {
+		histograms[w] = NewHistogram()
 		wg.Add(1)
-		go func(id int) {
+		go func(client model.Client, latency *Histogram) {
 			defer wg.Done()
-			msg := &model.Message{
-				ID:        fmt.Sprintf("msg-%d", id),
-				Timestamp: time.Now(),
-				Content:   "Hello, World!",
-				Number:    int64(id),
-				IsValid:   true,
-			}
+			for {
+				if !deadline.IsZero() && time.Now().After(deadline) {
+					return
+				}
+				n := int(next.Add(1) - 1)
+				if p.count > 0 && n >= p.count {
+					return
+				}
+
+				msg := generateTestMessage(p.firstID+n, r.opts.MessageSize)
+				sendStart := time.Now()
+				if p.rate > 0 {
+					// Latency counts from when the message was due, so a
+					// send delayed behind a slow predecessor is charged for
+					// the wait.
+					sendStart = p.intendedStart(start, n)
+					if !deadline.IsZero() && sendStart.After(deadline) {
+						return
+					}
+					if wait := time.Until(sendStart); wait > 0 {
+						time.Sleep(wait)
+					}
+				}
+				content.Add(int64(len(msg.Content)))
+				if err := r.send(client, msg); err != nil {
+					failures.add(err)
+				} else {
+					latency.Record(time.Since(sendStart))
+				}
 
-			if err := protocol.SendMessage(msg); err != nil {
-				errorCountMutex.Lock()
-				errorCount++
-				errorCountMutex.Unlock()
+				sent.Add(1)
 			}
-			msgChan <- struct{}{}
-		}(i)
+		}(client, histograms[w])
 	}
 
-	wg.Wait()
-	close(msgChan)
+	// Progress is sampled from the counters rather than reported by each
+	// send, which would put every worker behind one lock
+	stopProgress := func() {}
+	if progressFn != nil {
+		stopProgress = reportProgress(&sent, &failures, progressFn)
+	}
 
-	duration := time.Since(start)
-	messagesPerSecond := float64(r.messageCount) / duration.Seconds()
+	wg.Wait()
+	stopProgress()
 
-	return Result{
-		Protocol:          name,
-		TotalTime:         duration,
-		MessagesPerSecond: messagesPerSecond,
-		Errors:            errorCount,
-		Missing:           0,
+	result := phaseResult{
+		sent:    int(sent.Load()),
+		content: content.Load(),
+		errors:  failures.snapshot(),
+		buckets: failures.buckets(),
+		elapsed: time.Since(start),
+		latency: NewHistogram(),
+	}
+	for _, h := range histograms {
+		result.latency.Merge(h)
 	}
+	return result
 }
 
-func (r *Runner) RunBenchmarkWithProgress(progressFn func(sent, errors int)) []Result {
-	var results []Result
-
-	for name, protocol := range r.clients {
-		start := time.Now()
-		errors := 0
-		received := make(map[int]bool)
-
-		for i := 0; i < r.messageCount; i++ {
-			msg := generateTestMessage(i, r.messageSize)
-			if err := protocol.SendMessage(msg); err != nil {
-				errors++
-			} else {
-				received[i] = true
-			}
-
-			if progressFn != nil {
-				progressFn(i+1, errors)
-			}
-		}
-
-		duration := time.Since(start)
-		messagesPerSecond := float64(r.messageCount) / duration.Seconds()
-
-		// Check for missing messages
-		missing := 0
-		for i := 0; i < r.messageCount; i++ {
-			if !received[i] {
-				missing++
+// reportProgress calls progressFn every progressInterval until the returned
+// function is called, which reports once more and waits for the last call.
+func reportProgress(sent *atomic.Int64, failures *errorTally, progressFn func(sent int, errors ErrorCounts)) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				progressFn(int(sent.Load()), failures.snapshot())
+			case <-stop:
+				progressFn(int(sent.Load()), failures.snapshot())
+				return
 			}
 		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
 
-		results = append(results, Result{
-			Protocol:          name,
-			TotalTime:         duration,
-			MessagesPerSecond: messagesPerSecond,
-			Errors:            errors,
-			Missing:           missing,
-		})
+func (r *Runner) send(client model.Client, msg *model.Message) error {
+	ctx := context.Background()
+	if r.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+		defer cancel()
 	}
-	return results
+	return client.Send(ctx, msg)
 }
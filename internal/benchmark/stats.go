@@ -0,0 +1,286 @@
+package benchmark
+
+import (
+	"math"
+	"sort"
+)
+
+// Significance is the p-value threshold below which a difference between two
+// samples is reported as real rather than noise.
+const Significance = 0.05
+
+// Sample describes repeated measurements of one metric.
+type Sample struct {
+	Values []float64
+	Mean   float64
+	StdDev float64
+	// CILow and CIHigh bound the 95% confidence interval of the mean.
+	CILow  float64
+	CIHigh float64
+}
+
+func NewSample(values []float64) Sample {
+	s := Sample{Values: values}
+	n := len(values)
+	if n == 0 {
+		return s
+	}
+
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= float64(n)
+
+	if n < 2 {
+		s.CILow, s.CIHigh = s.Mean, s.Mean
+		return s
+	}
+	for _, v := range values {
+		d := v - s.Mean
+		s.StdDev += d * d
+	}
+	s.StdDev = math.Sqrt(s.StdDev / float64(n-1))
+
+	margin := studentTQuantile(0.975, float64(n-1)) * s.StdDev / math.Sqrt(float64(n))
+	s.CILow, s.CIHigh = s.Mean-margin, s.Mean+margin
+	return s
+}
+
+// Margin is the half-width of the confidence interval.
+func (s Sample) Margin() float64 {
+	return (s.CIHigh - s.CILow) / 2
+}
+
+// Summary aggregates every run of one protocol at one message size.
+type Summary struct {
+	Protocol    string
+	MessageSize int // in KB
+	Runs        int
+	Throughput  Sample // msgs/sec
+	P50         Sample // latency in nanoseconds
+	P90         Sample
+	P99         Sample
+	P999        Sample
+}
+
+type summaryKey struct {
+	protocol string
+	size     int
+}
+
+// Summarize groups results by protocol and message size, preserving
+// first-seen order.
+func Summarize(results []Result) []Summary {
+	var order []summaryKey
+	grouped := make(map[summaryKey][]Result)
+	for _, r := range results {
+		k := summaryKey{r.Protocol, r.MessageSize}
+		if _, ok := grouped[k]; !ok {
+			order = append(order, k)
+		}
+		grouped[k] = append(grouped[k], r)
+	}
+
+	summaries := make([]Summary, 0, len(order))
+	for _, k := range order {
+		runs := grouped[k]
+		metric := func(f func(Result) float64) Sample {
+			values := make([]float64, len(runs))
+			for i, r := range runs {
+				values[i] = f(r)
+			}
+			return NewSample(values)
+		}
+		summaries = append(summaries, Summary{
+			Protocol:    k.protocol,
+			MessageSize: k.size,
+			Runs:        len(runs),
+			Throughput:  metric(func(r Result) float64 { return r.MessagesPerSecond }),
+			P50:         metric(func(r Result) float64 { return float64(r.Latency.P50) }),
+			P90:         metric(func(r Result) float64 { return float64(r.Latency.P90) }),
+			P99:         metric(func(r Result) float64 { return float64(r.Latency.P99) }),
+			P999:        metric(func(r Result) float64 { return float64(r.Latency.P999) }),
+		})
+	}
+	return summaries
+}
+
+// Comparison contrasts one metric between two protocols at the same
+// message size.
+type Comparison struct {
+	MessageSize int
+	Metric      string
+	Faster      string
+	Slower      string
+	Delta       float64 // relative advantage of Faster over Slower
+	PValue      float64 // NaN when either side has fewer than two runs
+	Significant bool
+}
+
+// CompareAdjacent ranks protocols of each message size by throughput and
+// by p50 and p99 latency, and tests each protocol against the next one in
+// the ranking.
+func CompareAdjacent(summaries []Summary) []Comparison {
+	var sizes []int
+	bySize := make(map[int][]Summary)
+	for _, s := range summaries {
+		if _, ok := bySize[s.MessageSize]; !ok {
+			sizes = append(sizes, s.MessageSize)
+		}
+		bySize[s.MessageSize] = append(bySize[s.MessageSize], s)
+	}
+
+	var comparisons []Comparison
+	for _, size := range sizes {
+		comparisons = append(comparisons, compareRanked(bySize[size])...)
+	}
+	return comparisons
+}
+
+func compareRanked(summaries []Summary) []Comparison {
+	var comparisons []Comparison
+	metrics := []struct {
+		name           string
+		sample         func(Summary) Sample
+		higherIsBetter bool
+	}{
+		{"throughput", func(s Summary) Sample { return s.Throughput }, true},
+		{"p50 latency", func(s Summary) Sample { return s.P50 }, false},
+		{"p99 latency", func(s Summary) Sample { return s.P99 }, false},
+	}
+
+	for _, m := range metrics {
+		ranked := append([]Summary(nil), summaries...)
+		sort.SliceStable(ranked, func(i, j int) bool {
+			if m.higherIsBetter {
+				return m.sample(ranked[i]).Mean > m.sample(ranked[j]).Mean
+			}
+			return m.sample(ranked[i]).Mean < m.sample(ranked[j]).Mean
+		})
+
+		for i := 0; i+1 < len(ranked); i++ {
+			a, b := m.sample(ranked[i]), m.sample(ranked[i+1])
+			p := WelchTTest(a.Values, b.Values)
+			c := Comparison{
+				MessageSize: ranked[i].MessageSize,
+				Metric:      m.name,
+				Faster:      ranked[i].Protocol,
+				Slower:      ranked[i+1].Protocol,
+				PValue:      p,
+				Significant: !math.IsNaN(p) && p < Significance,
+			}
+			if b.Mean != 0 {
+				c.Delta = math.Abs(a.Mean-b.Mean) / b.Mean
+			}
+			comparisons = append(comparisons, c)
+		}
+	}
+	return comparisons
+}
+
+// WelchTTest returns the two-sided p-value for the hypothesis that a and b
+// share a mean, without assuming equal variances. It returns NaN when either
+// sample has fewer than two values.
+func WelchTTest(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN()
+	}
+	sa, sb := NewSample(a), NewSample(b)
+	va := sa.StdDev * sa.StdDev / float64(len(a))
+	vb := sb.StdDev * sb.StdDev / float64(len(b))
+
+	if va+vb == 0 {
+		if sa.Mean == sb.Mean {
+			return 1
+		}
+		return 0
+	}
+
+	t := (sa.Mean - sb.Mean) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+	return studentTTwoSided(t, df)
+}
+
+// studentTTwoSided returns P(|T| >= |t|) for Student's t with df degrees of
+// freedom.
+func studentTTwoSided(t, df float64) float64 {
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// studentTQuantile inverts the Student's t CDF by bisection. The bracket
+// grows first, since with df below 1 the tails reach far beyond any fixed
+// bound.
+func studentTQuantile(p, df float64) float64 {
+	target := 2 * (1 - p)
+	lo, hi := 0.0, 1000.0
+	for studentTTwoSided(hi, df) > target && hi < math.MaxFloat64/4 {
+		lo, hi = hi, hi*2
+	}
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		if studentTTwoSided(mid, df) > target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b).
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a + b)
+	lb, _ := math.Lgamma(a)
+	lc, _ := math.Lgamma(b)
+	front := math.Exp(la - lb - lc + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only on this side
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		for _, numerator := range [2]float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + numerator*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + numerator/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
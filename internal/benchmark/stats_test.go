@@ -0,0 +1,79 @@
+package benchmark
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStudentTQuantile(t *testing.T) {
+	tests := []struct {
+		p, df, want, tolerance float64
+	}{
+		{0.975, 10, 2.2281, 1e-4},
+		{0.975, 1, 12.7062, 1e-4},
+		{0.975, 2, 4.3027, 1e-4},
+		{0.975, 30, 2.0423, 1e-4},
+		{0.995, 5, 4.0321, 1e-4},
+		{0.975, 0.5, 164.558, 1e-2},
+	}
+	for _, tt := range tests {
+		if got := studentTQuantile(tt.p, tt.df); math.Abs(got-tt.want) > tt.tolerance {
+			t.Errorf("studentTQuantile(%v, %v) = %.5f, want %.5f", tt.p, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestStudentTQuantileSmallDF(t *testing.T) {
+	// Far beyond the initial bisection bracket
+	for _, df := range []float64{0.2, 0.1} {
+		q := studentTQuantile(0.975, df)
+		if p := studentTTwoSided(q, df); math.Abs(p-0.05) > 1e-6 {
+			t.Errorf("df %v: quantile %g has two-sided p %.8f, want 0.05", df, q, p)
+		}
+	}
+}
+
+func TestStudentTTwoSided(t *testing.T) {
+	tests := []struct {
+		t, df, want float64
+	}{
+		{0, 10, 1},
+		{2, 10, 0.07339},
+		{-2, 10, 0.07339},
+		{2.2281, 10, 0.05},
+		{1, 1, 0.5},
+		{1, 0.5, 0.60224},
+		{100, 0.5, 0.06414},
+	}
+	for _, tt := range tests {
+		if got := studentTTwoSided(tt.t, tt.df); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("studentTTwoSided(%v, %v) = %.5f, want %.5f", tt.t, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"unequal variances", []float64{1, 2, 3, 4}, []float64{2, 4, 6, 8, 10}, 0.06913},
+		{"identical", []float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		{"zero variance, same mean", []float64{5, 5, 5}, []float64{5, 5}, 1},
+		{"zero variance, different mean", []float64{5, 5, 5}, []float64{6, 6}, 0},
+		{"too few values", []float64{1}, []float64{1, 2}, math.NaN()},
+	}
+	for _, tt := range tests {
+		got := WelchTTest(tt.a, tt.b)
+		if math.IsNaN(tt.want) {
+			if !math.IsNaN(got) {
+				t.Errorf("%s: got %v, want NaN", tt.name, got)
+			}
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("%s: got %.5f, want %.5f", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"protobench/internal/model"
+)
+
+// Binary is a compact hand-written encoding with no field names or tags:
+//
+//	id        uvarint length + bytes
+//	timestamp int64 Unix nanoseconds, 0 for the zero time
+//	content   uvarint length + bytes
+//	number    varint
+//	is_valid  one byte
+//	checksum  uint32
+//
+// Fixed-width fields are big-endian.
+type Binary struct{}
+
+var errShortBinary = errors.New("binary message is truncated")
+
+func (Binary) Name() string { return "binary" }
+
+func (b Binary) Marshal(msg *model.Message) ([]byte, error) {
+	return b.Append(nil, msg), nil
+}
+
+// Append encodes msg onto the end of dst, letting callers reuse a buffer.
+func (Binary) Append(dst []byte, msg *model.Message) []byte {
+	var nanos int64
+	if !msg.Timestamp.IsZero() {
+		nanos = msg.Timestamp.UnixNano()
+	}
+	valid := byte(0)
+	if msg.IsValid {
+		valid = 1
+	}
+
+	dst = binary.AppendUvarint(dst, uint64(len(msg.ID)))
+	dst = append(dst, msg.ID...)
+	dst = binary.BigEndian.AppendUint64(dst, uint64(nanos))
+	dst = binary.AppendUvarint(dst, uint64(len(msg.Content)))
+	dst = append(dst, msg.Content...)
+	dst = binary.AppendVarint(dst, msg.Number)
+	dst = append(dst, valid)
+	return binary.BigEndian.AppendUint32(dst, msg.Checksum)
+}
+
+func (Binary) Unmarshal(data []byte, msg *model.Message) error {
+	r := binaryReader{data: data}
+	id := r.bytes()
+	nanos := int64(r.fixed(8))
+	content := r.bytes()
+	number := r.varint()
+	valid := r.fixed(1)
+	checksum := uint32(r.fixed(4))
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.data) > 0 {
+		return errors.New("binary message has trailing bytes")
+	}
+
+	*msg = model.Message{
+		ID:       string(id),
+		Content:  string(content),
+		Number:   number,
+		IsValid:  valid != 0,
+		Checksum: checksum,
+	}
+	if nanos != 0 {
+		msg.Timestamp = time.Unix(0, nanos)
+	}
+	return nil
+}
+
+// binaryReader consumes fields from the front of data, remembering the
+// first error so that a message can be decoded without checking each field.
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binaryReader) fail() {
+	if r.err == nil {
+		r.err = errShortBinary
+	}
+	r.data = nil
+}
+
+func (r *binaryReader) bytes() []byte {
+	n, size := binary.Uvarint(r.data)
+	if size <= 0 || n > uint64(len(r.data)-size) {
+		r.fail()
+		return nil
+	}
+	b := r.data[size : size+int(n)]
+	r.data = r.data[size+int(n):]
+	return b
+}
+
+func (r *binaryReader) varint() int64 {
+	v, size := binary.Varint(r.data)
+	if size <= 0 {
+		r.fail()
+		return 0
+	}
+	r.data = r.data[size:]
+	return v
+}
+
+// fixed reads a big-endian unsigned integer of width bytes.
+func (r *binaryReader) fixed(width int) uint64 {
+	if len(r.data) < width {
+		r.fail()
+		return 0
+	}
+	var v uint64
+	for _, b := range r.data[:width] {
+		v = v<<8 | uint64(b)
+	}
+	r.data = r.data[width:]
+	return v
+}
@@ -0,0 +1,109 @@
+// Package codec serializes model.Message independently of how the bytes
+// travel, so that encoding cost can be measured apart from transport cost.
+package codec
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+
+	"protobench/internal/model"
+	pb "protobench/internal/protocols/grpc/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Codec converts messages to and from bytes. Implementations are stateless
+// and safe for concurrent use.
+type Codec interface {
+	Name() string
+	Marshal(msg *model.Message) ([]byte, error)
+	Unmarshal(data []byte, msg *model.Message) error
+}
+
+var codecs = []Codec{JSON{}, XML{}, BSON{}, Protobuf{}, Binary{}}
+
+// All returns every codec.
+func All() []Codec {
+	return append([]Codec(nil), codecs...)
+}
+
+// Lookup finds a codec by case-insensitive name.
+func Lookup(name string) (Codec, bool) {
+	for _, c := range codecs {
+		if strings.EqualFold(c.Name(), name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+type JSON struct{}
+
+func (JSON) Name() string { return "json" }
+
+func (JSON) Marshal(msg *model.Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (JSON) Unmarshal(data []byte, msg *model.Message) error {
+	return json.Unmarshal(data, msg)
+}
+
+type XML struct{}
+
+func (XML) Name() string { return "xml" }
+
+func (XML) Marshal(msg *model.Message) ([]byte, error) {
+	return xml.Marshal(msg)
+}
+
+func (XML) Unmarshal(data []byte, msg *model.Message) error {
+	return xml.Unmarshal(data, msg)
+}
+
+type BSON struct{}
+
+func (BSON) Name() string { return "bson" }
+
+func (BSON) Marshal(msg *model.Message) ([]byte, error) {
+	return bson.Marshal(msg)
+}
+
+func (BSON) Unmarshal(data []byte, msg *model.Message) error {
+	return bson.Unmarshal(data, msg)
+}
+
+// Protobuf uses the same schema as the gRPC protocol.
+type Protobuf struct{}
+
+func (Protobuf) Name() string { return "protobuf" }
+
+func (Protobuf) Marshal(msg *model.Message) ([]byte, error) {
+	return proto.Marshal(&pb.Message{
+		Id:        msg.ID,
+		Timestamp: timestamppb.New(msg.Timestamp),
+		Content:   msg.Content,
+		Number:    msg.Number,
+		IsValid:   msg.IsValid,
+		Checksum:  msg.Checksum,
+	})
+}
+
+func (Protobuf) Unmarshal(data []byte, msg *model.Message) error {
+	var m pb.Message
+	if err := proto.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*msg = model.Message{
+		ID:        m.GetId(),
+		Timestamp: m.GetTimestamp().AsTime(),
+		Content:   m.GetContent(),
+		Number:    m.GetNumber(),
+		IsValid:   m.GetIsValid(),
+		Checksum:  m.GetChecksum(),
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrorKind classifies why a send failed.
+type ErrorKind int
+
+const (
+	ErrOther    ErrorKind = iota
+	ErrDial               // could not connect
+	ErrWrite              // failed while sending the request
+	ErrRead               // failed while reading the response or ack
+	ErrTimeout            // no response or ack in time
+	ErrRejected           // the server refused the message
+	ErrDecode             // the message or its response could not be decoded
+	ErrChecksum           // the server found the payload corrupted
+	NumErrorKinds
+)
+
+var errorKindNames = [NumErrorKinds]string{
+	"other", "dial", "write", "read", "timeout", "rejected", "decode", "checksum",
+}
+
+func (k ErrorKind) String() string {
+	if k < 0 || k >= NumErrorKinds {
+		return fmt.Sprintf("ErrorKind(%d)", int(k))
+	}
+	return errorKindNames[k]
+}
+
+// ParseErrorKind is the inverse of ErrorKind.String.
+func ParseErrorKind(name string) (ErrorKind, bool) {
+	for k, n := range errorKindNames {
+		if n == name {
+			return ErrorKind(k), true
+		}
+	}
+	return ErrOther, false
+}
+
+// SendError is a failed send tagged with its kind.
+type SendError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *SendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// Errorf returns a SendError of kind with a formatted message; %w wraps as
+// with fmt.Errorf.
+func Errorf(kind ErrorKind, format string, args ...any) error {
+	return &SendError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Classify returns the kind of a send error. Running out of time counts as
+// a timeout whatever the client was doing at the moment; other errors take
+// the kind the client tagged them with.
+func Classify(err error) ErrorKind {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeout
+	}
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.Kind
+	}
+	return ErrOther
+}
+
+// NetErrorKind picks dial, write or read from the failed network operation
+// inside err, or returns fallback when err carries none.
+func NetErrorKind(err error, fallback ErrorKind) ErrorKind {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		switch opErr.Op {
+		case "dial":
+			return ErrDial
+		case "write":
+			return ErrWrite
+		case "read":
+			return ErrRead
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"maps"
+	"sync"
+
+	"protobench/internal/wire"
+)
+
+// DeliveryReport is a server's view of the messages it received.
+type DeliveryReport struct {
+	Received   int `json:"received"`     // every message decoded, including repeats
+	Unique     int `json:"unique"`       // distinct message numbers
+	Duplicates int `json:"duplicates"`   // repeats of an already seen number
+	OutOfOrder int `json:"out_of_order"` // arrived after a higher number
+	Corrupted  int `json:"corrupted"`    // content did not match its checksum
+	Partial    int `json:"partial"`      // arriving in pieces and not yet complete
+	Abandoned  int `json:"abandoned"`    // given up on after arriving only in part
+
+	// Counters are protocol-specific events counted by the server
+	Counters map[string]int64 `json:"counters,omitempty"`
+
+	// Traffic is what crossed the server's sockets, including framing,
+	// headers and acknowledgements
+	Traffic wire.Stats `json:"traffic"`
+}
+
+// DeliveryTracker gives access to a server's ledger, which may live in
+// another process.
+type DeliveryTracker interface {
+	ResetDelivery() error
+	Delivery() (DeliveryReport, error)
+}
+
+// Ledger records the messages a server receives so that delivery can be
+// verified from the receiving side. It is safe for concurrent use.
+type Ledger struct {
+	mu      sync.Mutex
+	seen    map[int64]struct{}
+	highest int64
+	report  DeliveryReport
+	traffic wire.Counter
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{
+		seen:    make(map[int64]struct{}),
+		highest: -1,
+	}
+}
+
+// Record notes the arrival of message number; intact is false when its
+// payload failed verification.
+func (l *Ledger) Record(number int64, intact bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.report.Received++
+	if !intact {
+		l.report.Corrupted++
+	}
+
+	if _, ok := l.seen[number]; ok {
+		l.report.Duplicates++
+		return
+	}
+	l.seen[number] = struct{}{}
+	l.report.Unique++
+
+	if number < l.highest {
+		l.report.OutOfOrder++
+	} else {
+		l.highest = number
+	}
+}
+
+// SetPartial notes how many messages are currently only partly received,
+// for servers that reassemble messages from pieces.
+func (l *Ledger) SetPartial(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.report.Partial = n
+}
+
+// RecordAbandoned notes a partly received message that was given up on.
+func (l *Ledger) RecordAbandoned() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.report.Abandoned++
+}
+
+// Count adds n to a protocol-specific counter.
+func (l *Ledger) Count(name string, n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.report.Counters == nil {
+		l.report.Counters = make(map[string]int64)
+	}
+	l.report.Counters[name] += n
+}
+
+// Traffic returns the counter the server's sockets should count into.
+func (l *Ledger) Traffic() *wire.Counter {
+	return &l.traffic
+}
+
+func (l *Ledger) Report() DeliveryReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	report := l.report
+	report.Counters = maps.Clone(l.report.Counters)
+	report.Traffic = l.traffic.Stats()
+	return report
+}
+
+// Reset forgets everything recorded so far, e.g. after a warmup phase.
+func (l *Ledger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seen = make(map[int64]struct{})
+	l.highest = -1
+	l.report = DeliveryReport{}
+	l.traffic.Reset()
+}
+
+func (l *Ledger) ResetDelivery() error {
+	l.Reset()
+	return nil
+}
+
+func (l *Ledger) Delivery() (DeliveryReport, error) {
+	return l.Report(), nil
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"hash/crc32"
+	"time"
+)
 
 // Message represents the common message structure used across all protocols
 type Message struct {
@@ -9,12 +12,15 @@ type Message struct {
 	Content   string    `json:"content" bson:"content"`
 	Number    int64     `json:"number" bson:"number"`
 	IsValid   bool      `json:"is_valid" bson:"is_valid"`
+	Checksum  uint32    `json:"checksum" bson:"checksum"`
 }
 
-// Protocol defines the interface that all protocol implementations must satisfy
-type Protocol interface {
-	Name() string
-	StartServer() error
-	StopServer() error
-	SendMessage(msg *Message) error
+// ContentChecksum is the CRC32 carried in Message.Checksum.
+func ContentChecksum(content string) uint32 {
+	return crc32.ChecksumIEEE([]byte(content))
+}
+
+// Verify reports whether the message content matches its checksum.
+func (m *Message) Verify() bool {
+	return m.Checksum == ContentChecksum(m.Content)
 }
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"context"
+	"net"
+)
+
+// Client sends messages to a protocol's server. Implementations are safe
+// for concurrent use, though the benchmark gives every worker its own.
+type Client interface {
+	// Send delivers msg and returns once the server has acknowledged it, or
+	// ctx is done.
+	Send(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+// Server receives messages and records them in its ledger.
+type Server interface {
+	// Start binds the listen address and returns once clients can connect.
+	Start() error
+	Stop() error
+	// Addr returns the address clients should dial, including the actual
+	// port when the server was asked to listen on port 0.
+	Addr() string
+	Ledger() *Ledger
+}
+
+// DialAddr converts a bound listener address into one a local client can
+// dial, replacing an unspecified host such as "[::]" with localhost.
+func DialAddr(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// StatsReporter is implemented by clients that track protocol internals
+// worth reporting, such as retransmissions or window sizes. Stats returns
+// what accumulated since the previous call, so that a benchmark can drop
+// whatever happened during warmup.
+type StatsReporter interface {
+	Stats() ProtocolStats
+}
+
+// ProtocolStats holds named, protocol-specific statistics. Counters add up
+// across clients, gauges are averaged, and traces are time series.
+type ProtocolStats struct {
+	Counters map[string]int64        `json:"counters,omitempty"`
+	Gauges   map[string]float64      `json:"gauges,omitempty"`
+	Traces   map[string][]TracePoint `json:"traces,omitempty"`
+}
+
+// TracePoint is one sample of a trace, at an offset from the start of the
+// period the stats cover.
+type TracePoint struct {
+	At    time.Duration `json:"at_ns"`
+	Value float64       `json:"value"`
+}
+
+// MergeStats combines the stats of several clients of one protocol. Traces
+// are kept per client, suffixed with the client's index when there is more
+// than one, since interleaving them would make each unreadable.
+func MergeStats(all []ProtocolStats) ProtocolStats {
+	merged := ProtocolStats{
+		Counters: make(map[string]int64),
+		Gauges:   make(map[string]float64),
+		Traces:   make(map[string][]TracePoint),
+	}
+	gaugeCounts := make(map[string]int)
+	for i, s := range all {
+		for name, v := range s.Counters {
+			merged.Counters[name] += v
+		}
+		for name, v := range s.Gauges {
+			merged.Gauges[name] += v
+			gaugeCounts[name]++
+		}
+		for name, trace := range s.Traces {
+			if len(all) > 1 {
+				name = fmt.Sprintf("%s#%d", name, i)
+			}
+			merged.Traces[name] = trace
+		}
+	}
+	for name, n := range gaugeCounts {
+		merged.Gauges[name] /= float64(n)
+	}
+	return merged
+}
@@ -1,9 +1,12 @@
 package bson
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
+	"sync"
 
 	"protobench/internal/model"
 
@@ -11,54 +14,87 @@ import (
 )
 
 type Client struct {
-	conn   net.Conn
-	port   string
-	server *Server
+	mu   sync.Mutex
+	conn net.Conn
+	addr string
 }
 
-func NewClient(port string) *Client {
-	return &Client{
-		port:   port,
-		server: NewServer(port),
-	}
+func NewClient(addr string) *Client {
+	return &Client{addr: addr}
 }
 
-func (c *Client) StartServer() error {
-	return c.server.Start()
-}
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-func (c *Client) StopServer() error {
-	return c.server.Stop()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
 }
 
-func (c *Client) Name() string {
-	return "BSON"
-}
+func (c *Client) Send(ctx context.Context, msg *model.Message) error {
+	// Frames on the shared stream must not interleave
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-func (c *Client) SendMessage(msg *model.Message) error {
 	if c.conn == nil {
-		conn, err := net.Dial("tcp", ":"+c.port)
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", c.addr)
 		if err != nil {
-			return fmt.Errorf("failed to connect: %w", err)
+			return model.Errorf(model.ErrDial, "failed to connect: %w", err)
 		}
 		c.conn = conn
 	}
 
+	// A zero deadline clears any left over from a previous send
+	deadline, _ := ctx.Deadline()
+	c.conn.SetDeadline(deadline)
+
 	data, err := bson.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
+	ack, err := c.exchange(data)
+	if err != nil {
+		// The stream may be left mid-frame, so start over on a new one
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	switch ack {
+	case ackOK:
+	case ackDecodeError:
+		return model.Errorf(model.ErrDecode, "server could not decode message")
+	case ackChecksumError:
+		return model.Errorf(model.ErrChecksum, "server found checksum mismatch")
+	default:
+		return model.Errorf(model.ErrRejected, "server rejected message with ack %d", ack)
+	}
+
+	return nil
+}
+
+// exchange writes one frame and returns the server's ack byte.
+func (c *Client) exchange(data []byte) (byte, error) {
 	// Send length prefix
 	size := uint32(len(data))
 	if err := binary.Write(c.conn, binary.BigEndian, size); err != nil {
-		return fmt.Errorf("failed to send size: %w", err)
+		return 0, model.Errorf(model.ErrWrite, "failed to send size: %w", err)
 	}
 
 	// Send data
 	if _, err := c.conn.Write(data); err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		return 0, model.Errorf(model.ErrWrite, "failed to send message: %w", err)
 	}
 
-	return nil
+	// Wait for acknowledgment
+	ack := make([]byte, 1)
+	if _, err := io.ReadFull(c.conn, ack); err != nil {
+		return 0, model.Errorf(model.ErrRead, "failed to read ack: %w", err)
+	}
+	return ack[0], nil
 }
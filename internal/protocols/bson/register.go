@@ -0,0 +1,21 @@
+package bson
+
+import (
+	"protobench/internal/model"
+	"protobench/internal/registry"
+)
+
+func init() {
+	registry.Register(registry.Entry{
+		Name:         "BSON",
+		Description:  "Length-prefixed BSON documents over TCP",
+		DefaultPort:  "8084",
+		Capabilities: registry.Acked | registry.Connected | registry.Checksummed,
+		NewServer: func(addr string, _ registry.Options) (model.Server, error) {
+			return NewServer(addr), nil
+		},
+		NewClient: func(addr string, _ registry.Options) (model.Client, error) {
+			return NewClient(addr), nil
+		},
+	})
+}
@@ -7,21 +7,31 @@ import (
 	"net"
 
 	"protobench/internal/model"
+	"protobench/internal/wire"
 
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+// Each frame is answered with a one-byte ack.
+const (
+	ackDecodeError   byte = 0
+	ackOK            byte = 1
+	ackChecksumError byte = 2
+)
+
 type Server struct {
 	listener net.Listener
-	port     string
+	addr     string
+	ledger   *model.Ledger
 }
 
 func (s *Server) Start() error {
-	listener, err := net.Listen("tcp", ":"+s.port)
+	listener, err := wire.Listen("tcp", s.addr, s.ledger.Traffic())
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 	s.listener = listener
+	s.addr = model.DialAddr(listener.Addr())
 
 	go s.handleConnections()
 	return nil
@@ -55,11 +65,18 @@ func (s *Server) handleConnection(conn net.Conn) {
 
 		var msg model.Message
 		if err := bson.Unmarshal(data, &msg); err != nil {
+			conn.Write([]byte{ackDecodeError})
+			continue
+		}
+		intact := msg.Verify()
+		s.ledger.Record(msg.Number, intact)
+		if !intact {
+			conn.Write([]byte{ackChecksumError})
 			continue
 		}
 
 		// Send acknowledgment
-		conn.Write([]byte{1})
+		conn.Write([]byte{ackOK})
 	}
 }
 
@@ -70,8 +87,17 @@ func (s *Server) Stop() error {
 	return nil
 }
 
-func NewServer(port string) *Server {
+func (s *Server) Ledger() *model.Ledger {
+	return s.ledger
+}
+
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+func NewServer(addr string) *Server {
 	return &Server{
-		port: port,
+		addr:   addr,
+		ledger: model.NewLedger(),
 	}
 }
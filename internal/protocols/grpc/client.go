@@ -3,51 +3,64 @@ package grpc
 import (
 	"context"
 	"fmt"
-	"time"
+	"sync"
 
 	"protobench/internal/model"
 	"protobench/internal/protocols/grpc/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Client struct {
+	mu     sync.Mutex
 	conn   *grpc.ClientConn
 	client proto.MessageServiceClient
-	port   string
-	server *Server
+	addr   string
 }
 
-func NewClient(port string) *Client {
-	return &Client{
-		port:   port,
-		server: NewServer(port),
-	}
+func NewClient(addr string) *Client {
+	return &Client{addr: addr}
 }
 
-func (c *Client) StartServer() error {
-	return c.server.Start()
-}
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-func (c *Client) StopServer() error {
-	return c.server.Stop()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.client = nil
+	return err
 }
 
-func (c *Client) Name() string {
-	return "gRPC"
-}
+// messageClient lazily dials the server. The returned stub is safe for
+// concurrent use, so only the dial itself is serialised.
+func (c *Client) messageClient() (proto.MessageServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-func (c *Client) SendMessage(msg *model.Message) error {
 	if c.conn == nil {
-		conn, err := grpc.Dial(":"+c.port, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := grpc.Dial(c.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 		if err != nil {
-			return fmt.Errorf("failed to connect: %v", err)
+			return nil, model.Errorf(model.ErrDial, "failed to connect: %w", err)
 		}
 		c.conn = conn
 		c.client = proto.NewMessageServiceClient(conn)
 	}
+	return c.client, nil
+}
+
+func (c *Client) Send(ctx context.Context, msg *model.Message) error {
+	client, err := c.messageClient()
+	if err != nil {
+		return err
+	}
 
 	protoMsg := &proto.Message{
 		Id:        msg.ID,
@@ -55,11 +68,23 @@ func (c *Client) SendMessage(msg *model.Message) error {
 		Content:   msg.Content,
 		Number:    msg.Number,
 		IsValid:   msg.IsValid,
+		Checksum:  msg.Checksum,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-
-	_, err := c.client.SendMessage(ctx, protoMsg)
-	return err
+	if _, err := client.SendMessage(ctx, protoMsg); err != nil {
+		switch status.Code(err) {
+		case codes.DeadlineExceeded:
+			// Report an expired deadline the way the other transports do
+			return fmt.Errorf("send timed out: %w", context.DeadlineExceeded)
+		case codes.Unavailable:
+			return model.Errorf(model.ErrDial, "%w", err)
+		case codes.DataLoss:
+			return model.Errorf(model.ErrChecksum, "%w", err)
+		case codes.Canceled, codes.Unknown:
+			return err
+		default:
+			return model.Errorf(model.ErrRejected, "%w", err)
+		}
+	}
+	return nil
 }
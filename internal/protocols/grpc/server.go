@@ -3,30 +3,44 @@ package grpc
 import (
 	"context"
 	"fmt"
-	"net"
 
+	"protobench/internal/model"
 	"protobench/internal/protocols/grpc/proto"
+	"protobench/internal/wire"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
 	server *grpc.Server
-	port   string
+	addr   string
+	ledger *model.Ledger
 	proto.UnimplementedMessageServiceServer
 }
 
-func NewServer(port string) *Server {
+func NewServer(addr string) *Server {
 	return &Server{
-		port: port,
+		addr:   addr,
+		ledger: model.NewLedger(),
 	}
 }
 
+func (s *Server) Ledger() *model.Ledger {
+	return s.ledger
+}
+
+func (s *Server) Addr() string {
+	return s.addr
+}
+
 func (s *Server) Start() error {
-	lis, err := net.Listen("tcp", ":"+s.port)
+	lis, err := wire.Listen("tcp", s.addr, s.ledger.Traffic())
 	if err != nil {
 		return fmt.Errorf("failed to listen: %v", err)
 	}
+	s.addr = model.DialAddr(lis.Addr())
 
 	s.server = grpc.NewServer()
 	proto.RegisterMessageServiceServer(s.server, s)
@@ -43,6 +57,11 @@ func (s *Server) Stop() error {
 }
 
 func (s *Server) SendMessage(ctx context.Context, msg *proto.Message) (*proto.Response, error) {
+	intact := model.ContentChecksum(msg.GetContent()) == msg.GetChecksum()
+	s.ledger.Record(msg.GetNumber(), intact)
+	if !intact {
+		return nil, status.Error(codes.DataLoss, "checksum mismatch")
+	}
 	return &proto.Response{
 		Success: true,
 		Message: "Message received",
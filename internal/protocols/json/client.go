@@ -2,10 +2,10 @@ package json
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 
 	"protobench/internal/model"
 )
@@ -13,52 +13,55 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
-	port       string
-	server     *Server
 }
 
-func NewClient(port string) *Client {
+func NewClient(addr string) *Client {
 	return &Client{
-		baseURL: fmt.Sprintf("http://localhost:%s", port),
+		baseURL: "http://" + addr,
 		httpClient: &http.Client{
-			Timeout: 5 * time.Second,
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
 		},
-		port:   port,
-		server: NewServer(port),
 	}
 }
 
-func (c *Client) StartServer() error {
-	return c.server.Start()
-}
-
-func (c *Client) StopServer() error {
-	return c.server.Stop()
-}
-
-func (c *Client) Name() string {
-	return "JSON"
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
 }
 
-func (c *Client) SendMessage(msg *model.Message) error {
+func (c *Client) Send(ctx context.Context, msg *model.Message) error {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(
-		c.baseURL+"/message",
-		"application/json",
-		bytes.NewBuffer(data),
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/message", bytes.NewBuffer(data))
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return model.Errorf(model.NetErrorKind(err, model.ErrWrite), "failed to send message: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return model.Errorf(statusKind(resp.StatusCode), "unexpected status code: %d", resp.StatusCode)
 	}
 
 	return nil
 }
+
+// statusKind maps the server's response status to an error kind.
+func statusKind(code int) model.ErrorKind {
+	switch code {
+	case http.StatusBadRequest:
+		return model.ErrDecode
+	case http.StatusUnprocessableEntity:
+		return model.ErrChecksum
+	default:
+		return model.ErrRejected
+	}
+}
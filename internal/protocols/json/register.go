@@ -0,0 +1,21 @@
+package json
+
+import (
+	"protobench/internal/model"
+	"protobench/internal/registry"
+)
+
+func init() {
+	registry.Register(registry.Entry{
+		Name:         "JSON",
+		Description:  "JSON over HTTP/1.1 POST",
+		DefaultPort:  "8080",
+		Capabilities: registry.Acked | registry.Connected | registry.Checksummed,
+		NewServer: func(addr string, _ registry.Options) (model.Server, error) {
+			return NewServer(addr), nil
+		},
+		NewClient: func(addr string, _ registry.Options) (model.Client, error) {
+			return NewClient(addr), nil
+		},
+	})
+}
@@ -2,39 +2,53 @@ package json
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"sync"
 
 	"protobench/internal/model"
+	"protobench/internal/wire"
 )
 
 type Server struct {
 	server *http.Server
-	port   string
+	addr   string
 	wg     sync.WaitGroup
+	ledger *model.Ledger
 }
 
-func NewServer(port string) *Server {
+func NewServer(addr string) *Server {
 	return &Server{
-		port: port,
+		addr:   addr,
+		ledger: model.NewLedger(),
 	}
 }
 
+func (s *Server) Ledger() *model.Ledger {
+	return s.ledger
+}
+
+func (s *Server) Addr() string {
+	return s.addr
+}
+
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/message", s.handleMessage)
 
-	s.server = &http.Server{
-		Addr:    ":" + s.port,
-		Handler: mux,
+	// Listen before returning so that clients can connect immediately
+	listener, err := wire.Listen("tcp", s.addr, s.ledger.Traffic())
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
 	}
+	s.addr = model.DialAddr(listener.Addr())
+
+	s.server = &http.Server{Handler: mux}
 
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
-			panic(err)
-		}
+		s.server.Serve(listener)
 	}()
 
 	return nil
@@ -61,6 +75,12 @@ func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	intact := msg.Verify()
+	s.ledger.Record(msg.Number, intact)
+	if !intact {
+		http.Error(w, "checksum mismatch", http.StatusUnprocessableEntity)
+		return
+	}
 
 	// Echo the message back
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,87 @@
+// Package layered builds protocols from an independent codec and transport,
+// so that any encoding can be measured over any transport.
+package layered
+
+import (
+	"context"
+
+	"protobench/internal/codec"
+	"protobench/internal/model"
+	"protobench/internal/transport"
+)
+
+type Server struct {
+	addr      string
+	codec     codec.Codec
+	transport transport.Transport
+	listener  transport.Listener
+	ledger    *model.Ledger
+}
+
+func NewServer(addr string, c codec.Codec, t transport.Transport) *Server {
+	return &Server{
+		addr:      addr,
+		codec:     c,
+		transport: t,
+		ledger:    model.NewLedger(),
+	}
+}
+
+func (s *Server) Ledger() *model.Ledger {
+	return s.ledger
+}
+
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+func (s *Server) Start() error {
+	listener, err := s.transport.Listen(s.addr, s.ledger.Traffic(), s.handle)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.addr = listener.Addr()
+	return nil
+}
+
+func (s *Server) Stop() error {
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+func (s *Server) handle(frame []byte) transport.Status {
+	var msg model.Message
+	if err := s.codec.Unmarshal(frame, &msg); err != nil {
+		return transport.StatusDecodeError
+	}
+	intact := msg.Verify()
+	s.ledger.Record(msg.Number, intact)
+	if !intact {
+		return transport.StatusChecksumError
+	}
+	return transport.StatusOK
+}
+
+type Client struct {
+	codec codec.Codec
+	conn  transport.Conn
+}
+
+func NewClient(addr string, c codec.Codec, t transport.Transport) *Client {
+	return &Client{codec: c, conn: t.Dial(addr)}
+}
+
+func (c *Client) Send(ctx context.Context, msg *model.Message) error {
+	frame, err := c.codec.Marshal(msg)
+	if err != nil {
+		return model.Errorf(model.ErrDecode, "failed to marshal message: %w", err)
+	}
+	return c.conn.RoundTrip(ctx, frame)
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,37 @@
+package layered
+
+import (
+	"fmt"
+	"strconv"
+
+	"protobench/internal/codec"
+	"protobench/internal/model"
+	"protobench/internal/registry"
+	"protobench/internal/transport"
+)
+
+// basePorts gives each transport a block of default ports, one per codec.
+var basePorts = map[string]int{
+	"tcp":  8100,
+	"http": 8110,
+}
+
+func init() {
+	for _, t := range transport.All() {
+		for i, c := range codec.All() {
+			registry.Register(registry.Entry{
+				Name:         c.Name() + "+" + t.Name(),
+				Description:  fmt.Sprintf("%s codec over %s frames", c.Name(), t.Name()),
+				DefaultPort:  strconv.Itoa(basePorts[t.Name()] + i),
+				Capabilities: registry.Acked | registry.Connected | registry.Checksummed,
+				Extra:        true,
+				NewServer: func(addr string, _ registry.Options) (model.Server, error) {
+					return NewServer(addr, c, t), nil
+				},
+				NewClient: func(addr string, _ registry.Options) (model.Client, error) {
+					return NewClient(addr, c, t), nil
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package tcp
+
+import (
+	"protobench/internal/codec"
+	"protobench/internal/model"
+	"protobench/internal/protocols/layered"
+	"protobench/internal/registry"
+	"protobench/internal/transport"
+)
+
+// TCP is the compact binary codec over length-prefixed TCP frames, listed
+// among the main protocols as the leanest baseline.
+func init() {
+	registry.Register(registry.Entry{
+		Name:         "TCP",
+		Description:  "Length-prefixed compact binary messages over raw TCP",
+		DefaultPort:  "8083",
+		Capabilities: registry.Acked | registry.Connected | registry.Checksummed,
+		NewServer: func(addr string, _ registry.Options) (model.Server, error) {
+			return layered.NewServer(addr, codec.Binary{}, transport.TCP{}), nil
+		},
+		NewClient: func(addr string, _ registry.Options) (model.Client, error) {
+			return layered.NewClient(addr, codec.Binary{}, transport.TCP{}), nil
+		},
+	})
+}
@@ -1,108 +1,133 @@
 package udp
 
 import (
-	"encoding/binary"
-	"fmt"
+	"context"
 	"net"
+	"sync"
 	"time"
 
+	"protobench/internal/codec"
 	"protobench/internal/model"
 )
 
 type Client struct {
+	mu     sync.Mutex
 	conn   *net.UDPConn
-	addr   *net.UDPAddr
-	port   string
-	server *Server
+	addr   string
+	config Config
+	cc     *congestion
+	stats  clientStats
 }
 
-func NewClient(port string) *Client {
-	return &Client{
-		port:   port,
-		server: NewServer(port),
+// Config controls how hard the client tries to get each chunk acknowledged.
+type Config struct {
+	// Retries is how many times a chunk is sent before giving up.
+	Retries int
+	// AckTimeout is the retransmission timeout until the first round trip
+	// has been measured. After that the timeout follows the measured RTT
+	// within MinRTO and MaxRTO, doubling each time it expires.
+	AckTimeout time.Duration
+	MinRTO     time.Duration
+	MaxRTO     time.Duration
+	// Window caps how many chunks may be awaiting acknowledgement at once;
+	// 1 is stop-and-wait. Below the cap the congestion window adapts.
+	Window int
+	// FECGroup, when positive, follows every group of that many chunks with
+	// an XOR parity datagram from which the server can rebuild any one lost
+	// chunk of the group without waiting for a retransmit.
+	FECGroup int
+	// Loss is the fraction of outgoing datagrams dropped on purpose,
+	// simulating a lossy link.
+	Loss float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Retries:    8,
+		AckTimeout: 50 * time.Millisecond,
+		MinRTO:     time.Millisecond,
+		MaxRTO:     time.Second,
+		Window:     32,
 	}
 }
 
-func (c *Client) StartServer() error {
-	return c.server.Start()
+func NewClient(addr string) *Client {
+	return NewClientWithConfig(addr, DefaultConfig())
 }
 
-func (c *Client) StopServer() error {
-	return c.server.Stop()
+func NewClientWithConfig(addr string, config Config) *Client {
+	c := &Client{
+		addr:   addr,
+		config: config,
+		cc:     newCongestion(config),
+		stats:  newClientStats(),
+	}
+	c.stats.traceWindow(c.cc.window())
+	return c
 }
 
-func (c *Client) Name() string {
-	return "UDP"
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
 }
 
-const (
-	maxChunkSize = 1400 // Leave room for headers
-	headerSize   = 16   // 8 bytes seq + 8 bytes chunk info
-)
+func (c *Client) Send(ctx context.Context, msg *model.Message) error {
+	// Acks arrive on the one socket the client reads, so one message at a time
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-func (c *Client) SendMessage(msg *model.Message) error {
 	if c.conn == nil {
-		addr, err := net.ResolveUDPAddr("udp", ":"+c.port)
+		addr, err := net.ResolveUDPAddr("udp", c.addr)
 		if err != nil {
-			return fmt.Errorf("failed to resolve address: %w", err)
+			return model.Errorf(model.ErrDial, "failed to resolve address: %w", err)
 		}
 		conn, err := net.DialUDP("udp", nil, addr)
 		if err != nil {
-			return fmt.Errorf("failed to dial: %w", err)
+			return model.Errorf(model.ErrDial, "failed to dial: %w", err)
 		}
 		c.conn = conn
-		c.addr = addr
 	}
 
-	content := []byte(msg.Content)
-	totalChunks := (len(content) + maxChunkSize - 1) / maxChunkSize
-
-	// Send each chunk with retries
-	for chunk := 0; chunk < totalChunks; chunk++ {
-		start := chunk * maxChunkSize
-		end := start + maxChunkSize
-		if end > len(content) {
-			end = len(content)
-		}
-
-		// Header: sequence number (8 bytes) + chunk number (4 bytes) + total chunks (4 bytes)
-		header := make([]byte, headerSize)
-		binary.BigEndian.PutUint64(header[0:8], uint64(msg.Number))
-		binary.BigEndian.PutUint32(header[8:12], uint32(chunk))
-		binary.BigEndian.PutUint32(header[12:16], uint32(totalChunks))
-
-		// Combine header and chunk data
-		data := append(header, content[start:end]...)
-
-		// Try to send chunk with retries
-		maxRetries := 3
-		success := false
-		for retry := 0; retry < maxRetries; retry++ {
-			if err := c.sendChunkWithAck(data); err == nil {
-				success = true
-				break
-			}
-		}
-		if !success {
-			return fmt.Errorf("failed to send chunk %d/%d after retries", chunk+1, totalChunks)
-		}
-	}
-
-	return nil
+	// The whole message travels, split across as many datagrams as needed
+	payload := (codec.Binary{}).Append(nil, msg)
+	return newTransfer(c, uint64(msg.Number), payload).run(ctx)
 }
 
-func (c *Client) sendChunkWithAck(data []byte) error {
-	if _, err := c.conn.Write(data); err != nil {
-		return err
+// Stats reports retransmissions, parity, the round-trip estimate and the
+// congestion window since the previous call.
+func (c *Client) Stats() model.ProtocolStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := model.ProtocolStats{
+		Counters: map[string]int64{
+			"chunks_sent":       c.stats.chunksSent,
+			"retransmits":       c.stats.retransmits,
+			"ack_timeouts":      c.stats.ackTimeouts,
+			"window_reductions": c.stats.reductions,
+			"parity_sent":       c.stats.paritySent,
+			"datagrams_dropped": c.stats.dropped,
+		},
+		Gauges: map[string]float64{
+			"rto_us": float64(c.cc.rto.Microseconds()),
+			"cwnd":   c.cc.cwnd,
+			"window": float64(c.cc.window()),
+		},
+		Traces: map[string][]model.TracePoint{"window": c.stats.trace},
 	}
-
-	// Wait for acknowledgment
-	c.conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
-	ackBuf := make([]byte, headerSize)
-	n, err := c.conn.Read(ackBuf)
-	if err != nil || n != headerSize {
-		return fmt.Errorf("ack error: %v", err)
+	if c.cc.sampled {
+		stats.Gauges["srtt_us"] = float64(c.cc.srtt.Microseconds())
+		stats.Gauges["rttvar_us"] = float64(c.cc.rttvar.Microseconds())
 	}
 
-	return nil
+	c.stats = newClientStats()
+	c.stats.traceWindow(c.cc.window())
+	return stats
 }
@@ -0,0 +1,92 @@
+package udp
+
+import "time"
+
+const (
+	initialWindow = 4 // chunks in flight before any ack has arrived
+
+	// rtoGranularity stands in for the clock granularity G of RFC 6298,
+	// keeping the timeout above SRTT when RTTVAR is tiny
+	rtoGranularity = 100 * time.Microsecond
+)
+
+// congestion is a client's retransmission timer and congestion window. It
+// lives as long as the client, so what one message learns about the path
+// carries over to the next, as it would on a TCP connection.
+type congestion struct {
+	config Config
+
+	// RFC 6298 round-trip estimation
+	srtt    time.Duration
+	rttvar  time.Duration
+	rto     time.Duration
+	sampled bool
+
+	// AIMD with slow start, in chunks
+	cwnd          float64
+	ssthresh      float64
+	lastReduction time.Time
+}
+
+func newCongestion(config Config) *congestion {
+	return &congestion{
+		config:   config,
+		rto:      config.AckTimeout,
+		cwnd:     float64(min(initialWindow, config.Window)),
+		ssthresh: float64(config.Window),
+	}
+}
+
+// sample updates the estimate with the round-trip time of a chunk that was
+// sent only once (Karn's algorithm).
+func (c *congestion) sample(rtt time.Duration) {
+	if !c.sampled {
+		c.srtt = rtt
+		c.rttvar = rtt / 2
+		c.sampled = true
+	} else {
+		diff := c.srtt - rtt
+		if diff < 0 {
+			diff = -diff
+		}
+		c.rttvar = (3*c.rttvar + diff) / 4
+		c.srtt = (7*c.srtt + rtt) / 8
+	}
+	c.rto = min(max(c.srtt+max(rtoGranularity, 4*c.rttvar), c.config.MinRTO), c.config.MaxRTO)
+}
+
+// backoff doubles the timeout after it expires.
+func (c *congestion) backoff() {
+	c.rto = min(2*c.rto, c.config.MaxRTO)
+}
+
+// acked grows the window for n newly acknowledged chunks: by one chunk per
+// ack in slow start and by about one chunk per round trip after.
+func (c *congestion) acked(n int) {
+	for i := 0; i < n; i++ {
+		if c.cwnd < c.ssthresh {
+			c.cwnd++
+		} else {
+			c.cwnd += 1 / c.cwnd
+		}
+	}
+	c.cwnd = min(c.cwnd, float64(c.config.Window))
+}
+
+// lost halves the window for a chunk sent at sentAt that went unacked. It
+// reports false, changing nothing, when the chunk was sent before the last
+// reduction, so that one burst of losses halves the window only once.
+func (c *congestion) lost(sentAt time.Time) bool {
+	if sentAt.Before(c.lastReduction) {
+		return false
+	}
+	c.ssthresh = max(c.cwnd/2, 1)
+	c.cwnd = c.ssthresh
+	c.lastReduction = time.Now()
+	return true
+}
+
+// window is how many chunks may be in flight.
+func (c *congestion) window() int {
+	return max(1, int(c.cwnd))
+}
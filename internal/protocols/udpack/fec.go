@@ -0,0 +1,56 @@
+package udp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// xorChunk XORs chunk, prefixed with its length, into the parity block and
+// returns the block, grown as needed. Shorter chunks are padded with zeros.
+func xorChunk(block, chunk []byte) []byte {
+	for len(block) < 2+len(chunk) {
+		block = append(block, 0)
+	}
+	block[0] ^= byte(len(chunk) >> 8)
+	block[1] ^= byte(len(chunk))
+	for i, b := range chunk {
+		block[2+i] ^= b
+	}
+	return block
+}
+
+// appendParity appends the payload of a parity datagram for a group of
+// size chunks whose XOR is block.
+func appendParity(dst []byte, size int, block []byte) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, uint16(size))
+	return append(dst, block...)
+}
+
+// parseParity splits a parity payload into its group size and block.
+func parseParity(payload []byte) (int, []byte, error) {
+	if len(payload) < 4 {
+		return 0, nil, fmt.Errorf("parity too short: %d bytes", len(payload))
+	}
+	size := int(binary.BigEndian.Uint16(payload))
+	if size == 0 {
+		return 0, nil, fmt.Errorf("empty parity group")
+	}
+	return size, payload[2:], nil
+}
+
+// recoverChunk rebuilds the one chunk missing from a group by XORing the
+// chunks present into a copy of the group's parity block.
+func recoverChunk(block []byte, present [][]byte) ([]byte, error) {
+	rebuilt := append([]byte{}, block...)
+	for _, chunk := range present {
+		if 2+len(chunk) > len(rebuilt) {
+			return nil, fmt.Errorf("chunk of %d bytes exceeds parity", len(chunk))
+		}
+		rebuilt = xorChunk(rebuilt, chunk)
+	}
+	n := int(binary.BigEndian.Uint16(rebuilt))
+	if 2+n > len(rebuilt) {
+		return nil, fmt.Errorf("rebuilt length %d exceeds parity", n)
+	}
+	return rebuilt[2 : 2+n], nil
+}
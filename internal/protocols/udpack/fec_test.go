@@ -0,0 +1,140 @@
+package udp
+
+import (
+	"testing"
+	"time"
+)
+
+// parityFor builds the parity payload of each group of size chunks, as the
+// client does.
+func parityFor(chunks [][]byte, size int) [][]byte {
+	var parity [][]byte
+	for start := 0; start < len(chunks); start += size {
+		var block []byte
+		for _, chunk := range chunks[start:min(start+size, len(chunks))] {
+			block = xorChunk(block, chunk)
+		}
+		parity = append(parity, appendParity(nil, size, block))
+	}
+	return parity
+}
+
+// deliver feeds the data chunks not in lost, then each group's parity, or
+// the parity first when parityFirst is set.
+func deliver(s *Server, seq uint64, chunks, parity [][]byte, lost map[int]bool, parityFirst bool) {
+	now := time.Now()
+	sendParity := func() {
+		for g, p := range parity {
+			header := Header{Type: PacketParity, Seq: seq, Chunk: uint32(g), Total: uint32(len(chunks))}
+			s.handleChunk(testSender, header, p, now)
+		}
+	}
+	if parityFirst {
+		sendParity()
+	}
+	for c, chunk := range chunks {
+		if !lost[c] {
+			s.handleChunk(testSender, dataHeader(seq, c, len(chunks)), chunk, now)
+		}
+	}
+	if !parityFirst {
+		sendParity()
+	}
+}
+
+func TestParityRecoversOneChunkPerGroup(t *testing.T) {
+	// 4 groups of 3, the last partial and ending in a short chunk
+	chunks := testChunks(9, 9*maxChunkSize+maxChunkSize/2)
+	if len(chunks) != 10 || len(chunks[9]) >= maxChunkSize {
+		t.Fatalf("got %d chunks, last of %d bytes; want 10 with a short last", len(chunks), len(chunks[9]))
+	}
+	parity := parityFor(chunks, 3)
+
+	tests := []struct {
+		name        string
+		lost        []int
+		parityFirst bool
+	}{
+		{"one per group", []int{1, 3, 8, 9}, false},
+		{"short last chunk", []int{9}, false},
+		{"parity before data", []int{0, 5, 6, 9}, true},
+	}
+	for _, tt := range tests {
+		s := NewServer(":0")
+		lost := make(map[int]bool)
+		for _, c := range tt.lost {
+			lost[c] = true
+		}
+		deliver(s, 9, chunks, parity, lost, tt.parityFirst)
+
+		report := s.ledger.Report()
+		if report.Unique != 1 || report.Corrupted != 0 || report.Partial != 0 {
+			t.Errorf("%s: unique %d, corrupted %d, partial %d; want 1, 0, 0", tt.name, report.Unique, report.Corrupted, report.Partial)
+		}
+		if got := report.Counters["fec_recovered"]; got != int64(len(tt.lost)) {
+			t.Errorf("%s: fec_recovered = %d, want %d", tt.name, got, len(tt.lost))
+		}
+	}
+}
+
+func TestParityCannotRecoverTwoChunksInAGroup(t *testing.T) {
+	chunks := testChunks(4, 6*maxChunkSize)
+	s := NewServer(":0")
+	deliver(s, 4, chunks, parityFor(chunks, 4), map[int]bool{0: true, 2: true}, false)
+
+	report := s.ledger.Report()
+	if report.Unique != 0 || report.Partial != 1 {
+		t.Errorf("unique %d, partial %d; want 0, 1", report.Unique, report.Partial)
+	}
+	if got := report.Counters["fec_recovered"]; got != 0 {
+		t.Errorf("fec_recovered = %d, want 0", got)
+	}
+}
+
+func TestParityRejectsDisagreeingGroupSize(t *testing.T) {
+	chunks := testChunks(2, 6*maxChunkSize)
+	s := NewServer(":0")
+	now := time.Now()
+	total := uint32(len(chunks))
+
+	first := parityFor(chunks, 4)[0]
+	if s.handleChunk(testSender, Header{Type: PacketParity, Seq: 2, Chunk: 0, Total: total}, first, now) == nil {
+		t.Fatal("first parity was not acknowledged")
+	}
+	other := parityFor(chunks, 3)[1]
+	if s.handleChunk(testSender, Header{Type: PacketParity, Seq: 2, Chunk: 1, Total: total}, other, now) != nil {
+		t.Error("parity with a different group size was acknowledged")
+	}
+
+	assembler := s.messages[assemblyKey{sender: testSender, seq: 2}]
+	if assembler.group != 4 || len(assembler.parity) != 1 {
+		t.Errorf("group %d with %d parity blocks; want 4 with 1", assembler.group, len(assembler.parity))
+	}
+}
+
+func TestRecoverChunk(t *testing.T) {
+	chunks := [][]byte{[]byte("first chunk"), []byte("second"), []byte("3")}
+	var block []byte
+	for _, c := range chunks {
+		block = xorChunk(block, c)
+	}
+	for missing := range chunks {
+		var present [][]byte
+		for i, c := range chunks {
+			if i != missing {
+				present = append(present, c)
+			}
+		}
+		got, err := recoverChunk(block, present)
+		if err != nil || string(got) != string(chunks[missing]) {
+			t.Errorf("missing %d: got %q, %v; want %q", missing, got, err, chunks[missing])
+		}
+	}
+
+	if _, err := recoverChunk(block[:3], chunks[:1]); err == nil {
+		t.Error("chunk longer than the parity was accepted")
+	}
+	if _, _, err := parseParity(appendParity(nil, 0, block)); err == nil {
+		t.Error("parity for an empty group was accepted")
+	}
+}
@@ -1,105 +1,105 @@
 package udp
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"hash/crc32"
 )
 
+// Every datagram, data or ack, starts with the same header. The checksum
+// covers the header with the checksum field zeroed, followed by the
+// payload, so a datagram damaged anywhere is rejected as a whole.
+//
+//	magic    uint16
+//	version  uint8
+//	type     uint8
+//	seq      uint64  message number
+//	chunk    uint32
+//	total    uint32  chunks in the message
+//	checksum uint32  CRC32 (IEEE)
+//
+// A message is encoded with the binary codec and its bytes split across the
+// payloads of data datagrams. An ack echoes seq and total, sets chunk to the
+// cumulative count of chunks received without a gap, and carries a
+// selective ack bitmap as its payload: bit i, least significant first
+// within each byte, is set when chunk cumulative+1+i has arrived.
+//
+// With forward error correction on, each group of data chunks is followed
+// by a parity datagram whose chunk field is the group's index. Its payload
+// is the group size as a uint16 followed by the XOR of the group's chunks,
+// each prefixed with its length as a uint16, so that any one chunk of the
+// group can be rebuilt from the others.
 const (
 	MagicBytes = 0x4242 // Protocol identifier
-	Version    = 1      // Protocol version
-	HeaderSize = 12     // 2 magic + 2 version + 4 payload size + 4 checksum
+	Version    = 4      // Protocol version
+	HeaderSize = 24
+
+	maxChunkSize  = 1400 // payload bytes per datagram, keeping it under a typical MTU
+	maxParitySize = 2 + 2 + maxChunkSize
 )
 
-type Header struct {
-	Magic      uint16
-	Version    uint16
-	PayloadLen uint32
-	Checksum   uint32
-}
+// PacketType tells data datagrams from acks and parity.
+type PacketType uint8
 
-type MessageBody struct {
-	ID      string `json:"id"`
-	Content string `json:"content"`
-	Number  int64  `json:"number"`
-}
+const (
+	PacketData   PacketType = 1
+	PacketAck    PacketType = 2
+	PacketParity PacketType = 3
+)
 
-func calculateChecksum(data []byte) uint32 {
-	return crc32.ChecksumIEEE(data)
+type Header struct {
+	Type  PacketType
+	Seq   uint64
+	Chunk uint32
+	Total uint32
 }
 
-func EncodeMessage(payload []byte) ([]byte, error) {
-	checksum := calculateChecksum(payload)
-
-	header := Header{
-		Magic:      MagicBytes,
-		Version:    Version,
-		PayloadLen: uint32(len(payload)),
-		Checksum:   checksum,
-	}
-
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.BigEndian, header); err != nil {
-		return nil, fmt.Errorf("failed to write header: %w", err)
-	}
-
-	buf.Write(payload)
-	return buf.Bytes(), nil
+// EncodeDatagram appends a datagram with header h and payload to dst.
+func EncodeDatagram(dst []byte, h Header, payload []byte) []byte {
+	start := len(dst)
+	dst = binary.BigEndian.AppendUint16(dst, MagicBytes)
+	dst = append(dst, Version, byte(h.Type))
+	dst = binary.BigEndian.AppendUint64(dst, h.Seq)
+	dst = binary.BigEndian.AppendUint32(dst, h.Chunk)
+	dst = binary.BigEndian.AppendUint32(dst, h.Total)
+	dst = binary.BigEndian.AppendUint32(dst, 0)
+	dst = append(dst, payload...)
+
+	datagram := dst[start:]
+	binary.BigEndian.PutUint32(datagram[20:24], crc32.ChecksumIEEE(datagram))
+	return dst
 }
 
-func DecodeMessage(data []byte) ([]byte, error) {
+// DecodeDatagram validates a datagram and returns its header and payload,
+// which aliases data.
+func DecodeDatagram(data []byte) (Header, []byte, error) {
 	if len(data) < HeaderSize {
-		return nil, fmt.Errorf("message too short")
+		return Header{}, nil, fmt.Errorf("datagram too short: %d bytes", len(data))
 	}
-
-	var header Header
-	reader := bytes.NewReader(data[:HeaderSize])
-	if err := binary.Read(reader, binary.BigEndian, &header); err != nil {
-		return nil, fmt.Errorf("failed to read header: %w", err)
+	if magic := binary.BigEndian.Uint16(data[0:2]); magic != MagicBytes {
+		return Header{}, nil, fmt.Errorf("invalid magic bytes: %#x", magic)
 	}
-
-	if header.Magic != MagicBytes {
-		return nil, fmt.Errorf("invalid magic bytes")
+	if data[2] != Version {
+		return Header{}, nil, fmt.Errorf("unsupported version: %d", data[2])
 	}
 
-	if header.Version != Version {
-		return nil, fmt.Errorf("unsupported version: %d", header.Version)
+	// Checksum the datagram as it was sent, with the checksum field zeroed
+	want := binary.BigEndian.Uint32(data[20:24])
+	crc := crc32.ChecksumIEEE(data[:20])
+	crc = crc32.Update(crc, crc32.IEEETable, []byte{0, 0, 0, 0})
+	crc = crc32.Update(crc, crc32.IEEETable, data[HeaderSize:])
+	if crc != want {
+		return Header{}, nil, fmt.Errorf("checksum mismatch: got %x, want %x", crc, want)
 	}
 
-	expectedLen := int(header.PayloadLen) + HeaderSize
-	if len(data) != expectedLen {
-		return nil, fmt.Errorf("invalid message length: got %d, want %d", len(data), expectedLen)
+	h := Header{
+		Type:  PacketType(data[3]),
+		Seq:   binary.BigEndian.Uint64(data[4:12]),
+		Chunk: binary.BigEndian.Uint32(data[12:16]),
+		Total: binary.BigEndian.Uint32(data[16:20]),
 	}
-
-	payload := data[HeaderSize:]
-	checksum := calculateChecksum(payload)
-	if checksum != header.Checksum {
-		return nil, fmt.Errorf("checksum mismatch: got %x, want %x", checksum, header.Checksum)
+	if h.Type != PacketData && h.Type != PacketAck && h.Type != PacketParity {
+		return Header{}, nil, fmt.Errorf("unknown packet type: %d", h.Type)
 	}
-
-	return payload, nil
-}
-
-// FormatMessage creates a simple ASCII representation of the message
-func FormatMessage(msg MessageBody) string {
-	return fmt.Sprintf("ID:%s|CONTENT:%s|NUMBER:%d", msg.ID, msg.Content, msg.Number)
-}
-
-// ParseMessage parses the ASCII representation back into a MessageBody
-func ParseMessage(data string) (MessageBody, error) {
-	var msg MessageBody
-	var id, content string
-	var number int64
-
-	_, err := fmt.Sscanf(data, "ID:%s|CONTENT:%s|NUMBER:%d", &id, &content, &number)
-	if err != nil {
-		return msg, fmt.Errorf("failed to parse message: %w", err)
-	}
-
-	msg.ID = id
-	msg.Content = content
-	msg.Number = number
-	return msg, nil
+	return h, data[HeaderSize:], nil
 }
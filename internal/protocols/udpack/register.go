@@ -0,0 +1,83 @@
+package udp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"protobench/internal/model"
+	"protobench/internal/registry"
+)
+
+func init() {
+	defaults := DefaultConfig()
+	serverDefaults := DefaultServerConfig()
+	registry.Register(registry.Entry{
+		Name:         "UDP-ACK",
+		Description:  "Chunked UDP datagrams with selective acks, adaptive retransmission, congestion control and optional XOR parity",
+		DefaultPort:  "8082",
+		Capabilities: registry.Acked | registry.Checksummed,
+		Options: []registry.Option{
+			{Name: "retries", Default: strconv.Itoa(defaults.Retries), Usage: "Sends of each chunk before giving up"},
+			{Name: "ack-timeout", Default: defaults.AckTimeout.String(), Usage: "Retransmission timeout until the round trip has been measured"},
+			{Name: "window", Default: strconv.Itoa(defaults.Window), Usage: "Largest congestion window in chunks; 1 is stop-and-wait"},
+			{Name: "fec", Default: strconv.Itoa(defaults.FECGroup), Usage: "Chunks per XOR parity datagram for forward error correction; 0 turns it off"},
+			{Name: "loss", Default: strconv.FormatFloat(defaults.Loss, 'g', -1, 64), Usage: "Fraction of datagrams the client drops on purpose to simulate a lossy link"},
+			{Name: "assembly-timeout", Default: serverDefaults.AssemblyTimeout.String(), Usage: "How long the server keeps a partly received message"},
+		},
+		NewServer: func(addr string, opts registry.Options) (model.Server, error) {
+			timeout, err := opts.Duration("assembly-timeout")
+			if err != nil {
+				return nil, err
+			}
+			if timeout <= 0 {
+				return nil, fmt.Errorf("option assembly-timeout: must be positive")
+			}
+			return NewServerWithConfig(addr, ServerConfig{AssemblyTimeout: timeout}), nil
+		},
+		NewClient: func(addr string, opts registry.Options) (model.Client, error) {
+			retries, err := opts.Int("retries")
+			if err != nil {
+				return nil, err
+			}
+			if retries < 1 {
+				return nil, fmt.Errorf("option retries: must be at least 1")
+			}
+			ackTimeout, err := opts.Duration("ack-timeout")
+			if err != nil {
+				return nil, err
+			}
+			if ackTimeout <= 0 {
+				return nil, fmt.Errorf("option ack-timeout: must be positive")
+			}
+			window, err := opts.Int("window")
+			if err != nil {
+				return nil, err
+			}
+			if window < 1 {
+				return nil, fmt.Errorf("option window: must be at least 1")
+			}
+			fec, err := opts.Int("fec")
+			if err != nil {
+				return nil, err
+			}
+			if fec < 0 || fec > math.MaxUint16 {
+				return nil, fmt.Errorf("option fec: must be between 0 and %d", math.MaxUint16)
+			}
+			loss, err := opts.Float("loss")
+			if err != nil {
+				return nil, err
+			}
+			if loss < 0 || loss >= 1 {
+				return nil, fmt.Errorf("option loss: must be at least 0 and below 1")
+			}
+			config := DefaultConfig()
+			config.Retries = retries
+			config.AckTimeout = ackTimeout
+			config.Window = window
+			config.FECGroup = fec
+			config.Loss = loss
+			return NewClientWithConfig(addr, config), nil
+		},
+	})
+}
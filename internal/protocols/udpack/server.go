@@ -1,42 +1,90 @@
 package udp
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
+	"time"
+
+	"protobench/internal/codec"
+	"protobench/internal/model"
+	"protobench/internal/wire"
 )
 
+// maxChunks bounds the chunk count a header may claim, so that a corrupt or
+// hostile header cannot make the server allocate without limit.
+const maxChunks = 1 << 16
+
+// ServerConfig controls how long the server waits for the rest of a message.
+type ServerConfig struct {
+	// AssemblyTimeout is how long a partly received message may go without
+	// a new chunk before it is abandoned. Finished and abandoned messages
+	// are remembered for as long again so that late retransmits are not
+	// taken for new messages.
+	AssemblyTimeout time.Duration
+}
+
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{AssemblyTimeout: 2 * time.Second}
+}
+
 type Server struct {
-	conn     *net.UDPConn
-	port     string
-	messages map[uint64]*messageAssembler
+	conn     net.PacketConn
+	addr     string
+	config   ServerConfig
+	messages map[assemblyKey]*messageAssembler
+	partial  int // assemblies not yet complete
+	ledger   *model.Ledger
+}
+
+// assemblyKey tells apart messages from different clients that reuse a
+// sequence number.
+type assemblyKey struct {
+	sender string
+	seq    uint64
 }
 
 type messageAssembler struct {
-	chunks    map[uint32][]byte
-	total     uint32
+	chunks    [][]byte // by chunk number; nil until received
+	received  int
+	next      int // first chunk not yet received
+	highest   int // highest chunk received
+	group     int // chunks per parity group, once parity has arrived
+	parity    map[int][]byte
+	lastSeen  time.Time
 	completed bool
+	abandoned bool
+}
+
+func NewServer(addr string) *Server {
+	return NewServerWithConfig(addr, DefaultServerConfig())
 }
 
-func NewServer(port string) *Server {
+func NewServerWithConfig(addr string, config ServerConfig) *Server {
 	return &Server{
-		port:     port,
-		messages: make(map[uint64]*messageAssembler),
+		addr:     addr,
+		config:   config,
+		messages: make(map[assemblyKey]*messageAssembler),
+		ledger:   model.NewLedger(),
 	}
 }
 
-func (s *Server) Start() error {
-	addr, err := net.ResolveUDPAddr("udp", ":"+s.port)
-	if err != nil {
-		return fmt.Errorf("failed to resolve address: %w", err)
-	}
+func (s *Server) Ledger() *model.Ledger {
+	return s.ledger
+}
 
-	conn, err := net.ListenUDP("udp", addr)
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+func (s *Server) Start() error {
+	conn, err := wire.ListenPacket("udp", s.addr, s.ledger.Traffic())
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
 	s.conn = conn
+	s.addr = model.DialAddr(conn.LocalAddr())
 	go s.handleConnections()
 	return nil
 }
@@ -49,33 +97,223 @@ func (s *Server) Stop() error {
 }
 
 func (s *Server) handleConnections() {
-	buffer := make([]byte, maxChunkSize+headerSize)
+	buffer := make([]byte, HeaderSize+maxParitySize)
+	var ack, bitmap []byte
+	sweepEvery := s.config.AssemblyTimeout / 2
+	lastSweep := time.Now()
 	for {
-		n, remoteAddr, err := s.conn.ReadFromUDP(buffer)
+		// Wake up periodically even when idle so stale assemblies are
+		// evicted without waiting for more traffic
+		s.conn.SetReadDeadline(lastSweep.Add(sweepEvery))
+		n, remoteAddr, err := s.conn.ReadFrom(buffer)
+		now := time.Now()
+		if now.Sub(lastSweep) >= sweepEvery {
+			s.evictStale(now)
+			lastSweep = now
+		}
 		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
 			return
 		}
 
-		if n < headerSize {
+		// Damaged, foreign and inconsistent datagrams are dropped
+		// unacknowledged, leaving the client to retry or give up
+		header, payload, err := DecodeDatagram(buffer[:n])
+		if err != nil || header.Type == PacketAck {
 			continue
 		}
+		if assembler := s.handleChunk(remoteAddr.String(), header, payload, now); assembler != nil {
+			header.Type = PacketAck
+			header.Chunk = uint32(assembler.next)
+			bitmap = assembler.sack(bitmap[:0])
+			ack = EncodeDatagram(ack[:0], header, bitmap)
+			s.conn.WriteTo(ack, remoteAddr)
+		}
+	}
+}
 
-		// Extract header info
-		seqNum := binary.BigEndian.Uint64(buffer[0:8])
-		chunkNum := binary.BigEndian.Uint32(buffer[8:12])
-		totalChunks := binary.BigEndian.Uint32(buffer[12:16])
+// handleChunk stores one valid data or parity chunk and returns the
+// assembly to ack from, or nil if the chunk should go unacknowledged.
+func (s *Server) handleChunk(sender string, header Header, payload []byte, now time.Time) *messageAssembler {
+	if header.Total == 0 || header.Total > maxChunks || header.Chunk >= header.Total {
+		return nil
+	}
+
+	key := assemblyKey{sender: sender, seq: header.Seq}
+	assembler, exists := s.messages[key]
+	if !exists {
+		assembler = &messageAssembler{chunks: make([][]byte, header.Total)}
+		s.messages[key] = assembler
+		s.setPartial(s.partial + 1)
+	}
+	assembler.lastSeen = now
+
+	if assembler.completed {
+		// A retransmit whose ack was lost; ack it again
+		return assembler
+	}
+	if assembler.abandoned {
+		return nil
+	}
+	if uint32(len(assembler.chunks)) != header.Total {
+		// The header disagrees with the chunks already received
+		return nil
+	}
 
-		// Send acknowledgment
-		s.conn.WriteToUDP(buffer[:headerSize], remoteAddr)
+	var group int
+	if header.Type == PacketParity {
+		size, block, err := parseParity(payload)
+		if err != nil || (assembler.group != 0 && size != assembler.group) {
+			return nil
+		}
+		assembler.group = size
+		group = int(header.Chunk)
+		if group*size >= len(assembler.chunks) {
+			return nil
+		}
+		if assembler.parity == nil {
+			assembler.parity = make(map[int][]byte)
+		}
+		if _, ok := assembler.parity[group]; !ok {
+			assembler.parity[group] = append([]byte{}, block...)
+		}
+	} else {
+		assembler.store(int(header.Chunk), payload)
+		if assembler.group == 0 {
+			return s.finish(assembler, header.Seq)
+		}
+		group = int(header.Chunk) / assembler.group
+	}
 
-		// Store chunk
-		_, exists := s.messages[seqNum]
-		if !exists {
-			s.messages[seqNum] = &messageAssembler{
-				chunks: make(map[uint32][]byte),
-				total:  totalChunks,
+	if chunk, data := assembler.recover(group); data != nil {
+		assembler.store(chunk, data)
+		s.ledger.Count("fec_recovered", 1)
+	}
+	return s.finish(assembler, header.Seq)
+}
+
+// finish completes the message once every chunk is in and returns the
+// assembly to ack from.
+func (s *Server) finish(assembler *messageAssembler, seq uint64) *messageAssembler {
+	if assembler.received == len(assembler.chunks) {
+		s.complete(assembler, seq)
+	}
+	return assembler
+}
+
+func (a *messageAssembler) store(chunk int, payload []byte) {
+	if a.chunks[chunk] != nil {
+		return
+	}
+	a.chunks[chunk] = append([]byte{}, payload...)
+	a.received++
+	a.highest = max(a.highest, chunk)
+	for a.next < len(a.chunks) && a.chunks[a.next] != nil {
+		a.next++
+	}
+}
+
+// recover rebuilds the chunk of a group that is the only one missing, once
+// the group's parity has arrived. It returns nil data when there is nothing
+// to rebuild or the parity does not fit the chunks received.
+func (a *messageAssembler) recover(group int) (int, []byte) {
+	block, ok := a.parity[group]
+	if !ok {
+		return 0, nil
+	}
+	start := group * a.group
+	end := min(start+a.group, len(a.chunks))
+	missing := -1
+	var present [][]byte
+	for i := start; i < end; i++ {
+		if a.chunks[i] == nil {
+			if missing >= 0 {
+				return 0, nil
 			}
+			missing = i
+			continue
 		}
-		s.messages[seqNum].chunks[chunkNum] = append([]byte{}, buffer[headerSize:n]...)
+		present = append(present, a.chunks[i])
+	}
+	if missing < 0 {
+		return 0, nil
+	}
+	data, err := recoverChunk(block, present)
+	if err != nil {
+		return 0, nil
 	}
+	return missing, data
+}
+
+// sack appends the selective ack bitmap for the chunks received beyond the
+// first gap.
+func (a *messageAssembler) sack(dst []byte) []byte {
+	if a.completed || a.highest <= a.next {
+		return dst
+	}
+	// Bits for chunks next+1 through highest, capped to fit one datagram
+	n := min(a.highest-a.next, maxChunkSize*8)
+	for i := 0; i < n; i += 8 {
+		var b byte
+		for j := 0; j < 8 && i+j < n; j++ {
+			if a.chunks[a.next+1+i+j] != nil {
+				b |= 1 << j
+			}
+		}
+		dst = append(dst, b)
+	}
+	return dst
+}
+
+// complete reassembles and decodes a fully received message and records it.
+// Each datagram was checksummed already, so a message that fails to decode,
+// carries another number or does not match its own checksum was corrupted
+// before it was sent.
+func (s *Server) complete(assembler *messageAssembler, seq uint64) {
+	size := 0
+	for _, chunk := range assembler.chunks {
+		size += len(chunk)
+	}
+	data := make([]byte, 0, size)
+	for _, chunk := range assembler.chunks {
+		data = append(data, chunk...)
+	}
+
+	var msg model.Message
+	err := (codec.Binary{}).Unmarshal(data, &msg)
+	intact := err == nil && msg.Number == int64(seq) && msg.Verify()
+	s.ledger.Record(int64(seq), intact)
+
+	assembler.completed = true
+	assembler.chunks = nil
+	assembler.parity = nil
+	s.setPartial(s.partial - 1)
+}
+
+// evictStale abandons assemblies that have seen no chunk for
+// AssemblyTimeout, and forgets finished or abandoned ones after as long.
+func (s *Server) evictStale(now time.Time) {
+	for key, assembler := range s.messages {
+		if now.Sub(assembler.lastSeen) < s.config.AssemblyTimeout {
+			continue
+		}
+		if assembler.completed || assembler.abandoned {
+			delete(s.messages, key)
+			continue
+		}
+		assembler.abandoned = true
+		assembler.chunks = nil
+		assembler.parity = nil
+		assembler.lastSeen = now
+		s.ledger.RecordAbandoned()
+		s.setPartial(s.partial - 1)
+	}
+}
+
+func (s *Server) setPartial(n int) {
+	s.partial = n
+	s.ledger.SetPartial(n)
 }
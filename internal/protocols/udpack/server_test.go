@@ -0,0 +1,165 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"protobench/internal/codec"
+	"protobench/internal/model"
+)
+
+const testSender = "127.0.0.1:40000"
+
+// testChunks encodes message seq with roughly contentSize bytes of content
+// and splits it the way the client does.
+func testChunks(seq uint64, contentSize int) [][]byte {
+	msg := &model.Message{
+		ID:        "msg",
+		Timestamp: time.Unix(1700000000, 0),
+		Content:   strings.Repeat("x", contentSize),
+		Number:    int64(seq),
+		IsValid:   true,
+	}
+	msg.Checksum = model.ContentChecksum(msg.Content)
+	payload := (codec.Binary{}).Append(nil, msg)
+
+	var chunks [][]byte
+	for start := 0; start < len(payload); start += maxChunkSize {
+		chunks = append(chunks, payload[start:min(start+maxChunkSize, len(payload))])
+	}
+	return chunks
+}
+
+func dataHeader(seq uint64, chunk, total int) Header {
+	return Header{Type: PacketData, Seq: seq, Chunk: uint32(chunk), Total: uint32(total)}
+}
+
+func TestHandleChunkReassembles(t *testing.T) {
+	s := NewServer(":0")
+	now := time.Now()
+	chunks := testChunks(3, 3*maxChunkSize)
+
+	// Out of order, with a duplicate
+	order := []int{2, 0, 2, 3, 1}
+	for i, c := range order {
+		if s.handleChunk(testSender, dataHeader(3, c, len(chunks)), chunks[c], now) == nil {
+			t.Fatalf("chunk %d went unacknowledged", c)
+		}
+		if i < len(order)-1 && s.ledger.Report().Partial != 1 {
+			t.Fatalf("after chunk %d: partial = %d, want 1", c, s.ledger.Report().Partial)
+		}
+	}
+
+	report := s.ledger.Report()
+	if report.Unique != 1 || report.Corrupted != 0 || report.Partial != 0 {
+		t.Errorf("got unique %d, corrupted %d, partial %d; want 1, 0, 0", report.Unique, report.Corrupted, report.Partial)
+	}
+}
+
+func TestHandleChunkRecordsWrongNumberAsCorrupted(t *testing.T) {
+	s := NewServer(":0")
+	chunks := testChunks(5, 100)
+
+	// The datagrams claim seq 6, the message inside is number 5
+	s.handleChunk(testSender, dataHeader(6, 0, len(chunks)), chunks[0], time.Now())
+	if report := s.ledger.Report(); report.Unique != 1 || report.Corrupted != 1 {
+		t.Errorf("got unique %d, corrupted %d; want 1, 1", report.Unique, report.Corrupted)
+	}
+}
+
+func TestHandleChunkRejectsBadHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header Header
+	}{
+		{"no chunks", Header{Type: PacketData, Seq: 1, Chunk: 0, Total: 0}},
+		{"too many chunks", Header{Type: PacketData, Seq: 1, Chunk: 0, Total: maxChunks + 1}},
+		{"chunk equals total", Header{Type: PacketData, Seq: 1, Chunk: 2, Total: 2}},
+		{"chunk beyond total", Header{Type: PacketData, Seq: 1, Chunk: 9, Total: 2}},
+	}
+	for _, tt := range tests {
+		s := NewServer(":0")
+		if s.handleChunk(testSender, tt.header, []byte("data"), time.Now()) != nil {
+			t.Errorf("%s: chunk was acknowledged", tt.name)
+		}
+		if len(s.messages) != 0 || s.ledger.Report().Partial != 0 {
+			t.Errorf("%s: an assembly was started", tt.name)
+		}
+	}
+}
+
+func TestHandleChunkRejectsTotalMismatch(t *testing.T) {
+	s := NewServer(":0")
+	now := time.Now()
+	chunks := testChunks(1, 2*maxChunkSize)
+
+	s.handleChunk(testSender, dataHeader(1, 0, len(chunks)), chunks[0], now)
+	if s.handleChunk(testSender, dataHeader(1, 1, len(chunks)+1), chunks[1], now) != nil {
+		t.Fatal("chunk with a different total was acknowledged")
+	}
+	assembler := s.messages[assemblyKey{sender: testSender, seq: 1}]
+	if assembler.received != 1 {
+		t.Errorf("received = %d, want 1", assembler.received)
+	}
+}
+
+func TestHandleChunkReacksCompleted(t *testing.T) {
+	s := NewServer(":0")
+	now := time.Now()
+	chunks := testChunks(1, 2*maxChunkSize)
+	for c := range chunks {
+		s.handleChunk(testSender, dataHeader(1, c, len(chunks)), chunks[c], now)
+	}
+
+	// A retransmit after its ack was lost
+	assembler := s.handleChunk(testSender, dataHeader(1, 0, len(chunks)), chunks[0], now)
+	if assembler == nil || !assembler.completed || assembler.next != len(chunks) {
+		t.Fatalf("retransmit of a completed message was not acked as complete")
+	}
+	if report := s.ledger.Report(); report.Unique != 1 || report.Received != 1 {
+		t.Errorf("got received %d, unique %d; want 1, 1", report.Received, report.Unique)
+	}
+}
+
+func TestEvictStale(t *testing.T) {
+	timeout := 2 * time.Second
+	s := NewServerWithConfig(":0", ServerConfig{AssemblyTimeout: timeout})
+	start := time.Now()
+	chunks := testChunks(1, 2*maxChunkSize)
+	done := testChunks(2, 10)
+
+	s.handleChunk(testSender, dataHeader(1, 0, len(chunks)), chunks[0], start)
+	s.handleChunk(testSender, dataHeader(2, 0, len(done)), done[0], start)
+
+	s.evictStale(start.Add(timeout / 2))
+	if report := s.ledger.Report(); report.Partial != 1 || report.Abandoned != 0 {
+		t.Fatalf("before the timeout: partial %d, abandoned %d; want 1, 0", report.Partial, report.Abandoned)
+	}
+
+	abandonedAt := start.Add(timeout)
+	s.evictStale(abandonedAt)
+	if report := s.ledger.Report(); report.Partial != 0 || report.Abandoned != 1 || report.Unique != 1 {
+		t.Fatalf("at the timeout: partial %d, abandoned %d, unique %d; want 0, 1, 1", report.Partial, report.Abandoned, report.Unique)
+	}
+	if len(s.messages) != 1 {
+		t.Fatalf("%d assemblies remembered, want only the abandoned one", len(s.messages))
+	}
+
+	// A late retransmit neither revives the message nor counts it again
+	if s.handleChunk(testSender, dataHeader(1, 1, len(chunks)), chunks[1], abandonedAt.Add(timeout/2)) != nil {
+		t.Error("chunk of an abandoned message was acknowledged")
+	}
+	if report := s.ledger.Report(); report.Partial != 0 || report.Abandoned != 1 || report.Unique != 1 {
+		t.Errorf("after a late chunk: partial %d, abandoned %d, unique %d; want 0, 1, 1", report.Partial, report.Abandoned, report.Unique)
+	}
+
+	// Forgotten once it has gone quiet for as long again
+	s.evictStale(abandonedAt.Add(timeout/2 + timeout))
+	if len(s.messages) != 0 {
+		t.Errorf("%d assemblies remembered, want none", len(s.messages))
+	}
+	if report := s.ledger.Report(); report.Abandoned != 1 {
+		t.Errorf("abandoned = %d after forgetting, want 1", report.Abandoned)
+	}
+}
@@ -0,0 +1,33 @@
+package udp
+
+import (
+	"time"
+
+	"protobench/internal/model"
+)
+
+// maxTracePoints bounds the window trace of a long run.
+const maxTracePoints = 4096
+
+type clientStats struct {
+	start       time.Time
+	chunksSent  int64
+	retransmits int64
+	ackTimeouts int64
+	reductions  int64
+	paritySent  int64
+	dropped     int64 // by loss injection
+	trace       []model.TracePoint
+}
+
+func newClientStats() clientStats {
+	return clientStats{start: time.Now()}
+}
+
+// traceWindow records the congestion window when it has changed.
+func (s *clientStats) traceWindow(window int) {
+	if n := len(s.trace); n >= maxTracePoints || (n > 0 && s.trace[n-1].Value == float64(window)) {
+		return
+	}
+	s.trace = append(s.trace, model.TracePoint{At: time.Since(s.start), Value: float64(window)})
+}
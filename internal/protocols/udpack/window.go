@@ -0,0 +1,243 @@
+package udp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"protobench/internal/model"
+)
+
+// transfer sends one message as a sliding window of chunks. The client's
+// congestion window sets how many are in flight at once; every ack carries
+// the server's cumulative count plus a bitmap of the chunks it holds beyond
+// it, so only chunks still missing when the retransmission timeout expires
+// are sent again. With forward error correction, each group of chunks is
+// followed by a parity datagram as soon as the group has been sent once.
+type transfer struct {
+	conn    *net.UDPConn
+	config  Config
+	cc      *congestion
+	stats   *clientStats
+	seq     uint64
+	payload []byte
+	total   int
+
+	acked  []bool
+	sends  []int
+	sentAt []time.Time
+	base   int // first unacked chunk
+	next   int // first chunk never sent
+
+	parity   []byte // XOR of the current group's chunks
+	scratch  []byte
+	datagram []byte
+	ackBuf   []byte
+	lastErr  error
+}
+
+func newTransfer(c *Client, seq uint64, payload []byte) *transfer {
+	total := (len(payload) + maxChunkSize - 1) / maxChunkSize
+	return &transfer{
+		conn:     c.conn,
+		config:   c.config,
+		cc:       c.cc,
+		stats:    &c.stats,
+		seq:      seq,
+		payload:  payload,
+		total:    total,
+		acked:    make([]bool, total),
+		sends:    make([]int, total),
+		sentAt:   make([]time.Time, total),
+		datagram: make([]byte, 0, HeaderSize+maxParitySize),
+		ackBuf:   make([]byte, HeaderSize+maxChunkSize),
+	}
+}
+
+func (t *transfer) run(ctx context.Context) error {
+	for t.base < t.total {
+		for t.next < t.total && t.next < t.base+t.cc.window() {
+			t.send(t.next)
+			if t.config.FECGroup > 0 {
+				t.protect(t.next)
+			}
+			t.next++
+		}
+
+		// Resend what has gone unacknowledged for too long and wait no
+		// longer than the next chunk falls due
+		now := time.Now()
+		expired := false
+		var wake time.Time
+		for i := t.base; i < t.next; i++ {
+			if t.acked[i] {
+				continue
+			}
+			due := t.sentAt[i].Add(t.cc.rto)
+			if !now.Before(due) {
+				if t.sends[i] >= t.config.Retries {
+					return t.fail(i)
+				}
+				t.stats.ackTimeouts++
+				if t.cc.lost(t.sentAt[i]) {
+					t.stats.reductions++
+					t.stats.traceWindow(t.cc.window())
+				}
+				expired = true
+				t.send(i)
+				due = t.sentAt[i].Add(t.cc.rto)
+			}
+			if wake.IsZero() || due.Before(wake) {
+				wake = due
+			}
+		}
+		if expired {
+			t.cc.backoff()
+		}
+		if d, ok := ctx.Deadline(); ok && d.Before(wake) {
+			wake = d
+		}
+
+		t.conn.SetReadDeadline(wake)
+		n, err := t.conn.Read(t.ackBuf)
+		if err != nil {
+			// A deadline that cut the ack wait short is a timeout rather
+			// than a lost chunk, unless nothing was listening all along
+			if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+				if errors.Is(t.lastErr, syscall.ECONNREFUSED) {
+					return t.fail(t.base)
+				}
+				return fmt.Errorf("chunk %d/%d: %w", t.base+1, t.total, context.DeadlineExceeded)
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// Keep a refusal even when later reads only time out
+			if !errors.Is(t.lastErr, syscall.ECONNREFUSED) {
+				t.lastErr = fmt.Errorf("ack error: %w", err)
+			}
+			continue
+		}
+		t.handleAck(t.ackBuf[:n])
+	}
+	return nil
+}
+
+func (t *transfer) send(chunk int) {
+	start := chunk * maxChunkSize
+	end := min(start+maxChunkSize, len(t.payload))
+	header := Header{
+		Type:  PacketData,
+		Seq:   t.seq,
+		Chunk: uint32(chunk),
+		Total: uint32(t.total),
+	}
+	t.datagram = EncodeDatagram(t.datagram[:0], header, t.payload[start:end])
+	t.write()
+	t.stats.chunksSent++
+	if t.sends[chunk] > 0 {
+		t.stats.retransmits++
+	}
+	t.sends[chunk]++
+	t.sentAt[chunk] = time.Now()
+}
+
+// protect adds a chunk sent for the first time to its group's parity and
+// sends the parity once the group is complete.
+func (t *transfer) protect(chunk int) {
+	start := chunk * maxChunkSize
+	end := min(start+maxChunkSize, len(t.payload))
+	t.parity = xorChunk(t.parity, t.payload[start:end])
+
+	size := t.config.FECGroup
+	if (chunk+1)%size != 0 && chunk+1 != t.total {
+		return
+	}
+	header := Header{
+		Type:  PacketParity,
+		Seq:   t.seq,
+		Chunk: uint32(chunk / size),
+		Total: uint32(t.total),
+	}
+	t.scratch = appendParity(t.scratch[:0], size, t.parity)
+	t.datagram = EncodeDatagram(t.datagram[:0], header, t.scratch)
+	t.write()
+	t.stats.paritySent++
+	t.parity = t.parity[:0]
+}
+
+// write sends the encoded datagram unless loss injection drops it. A failed
+// write is retried like a lost datagram.
+func (t *transfer) write() {
+	if t.config.Loss > 0 && rand.Float64() < t.config.Loss {
+		t.stats.dropped++
+		return
+	}
+	if _, err := t.conn.Write(t.datagram); err != nil {
+		t.lastErr = err
+	}
+}
+
+// handleAck applies an ack's cumulative count and selective bitmap. Acks
+// that are damaged or belong to an earlier message are ignored.
+func (t *transfer) handleAck(datagram []byte) {
+	ack, bitmap, err := DecodeDatagram(datagram)
+	if err != nil || ack.Type != PacketAck || ack.Seq != t.seq {
+		return
+	}
+
+	cumulative := min(int(ack.Chunk), t.total)
+	newly := 0
+	var latest time.Time // send time of the newest chunk sent only once
+	markAcked := func(chunk int) {
+		if t.acked[chunk] || t.sends[chunk] == 0 {
+			return
+		}
+		t.acked[chunk] = true
+		newly++
+		if t.sends[chunk] == 1 && t.sentAt[chunk].After(latest) {
+			latest = t.sentAt[chunk]
+		}
+	}
+	for i := t.base; i < cumulative; i++ {
+		markAcked(i)
+	}
+	for i := 0; i < len(bitmap)*8; i++ {
+		chunk := cumulative + 1 + i
+		if chunk >= t.total {
+			break
+		}
+		if bitmap[i/8]&(1<<(i%8)) != 0 {
+			markAcked(chunk)
+		}
+	}
+	for t.base < t.total && t.acked[t.base] {
+		t.base++
+	}
+
+	if newly > 0 {
+		if !latest.IsZero() {
+			t.cc.sample(time.Since(latest))
+		}
+		t.cc.acked(newly)
+		t.stats.traceWindow(t.cc.window())
+	}
+}
+
+func (t *transfer) fail(chunk int) error {
+	err := t.lastErr
+	if err == nil {
+		err = errors.New("no ack")
+	}
+	// An ack that never came counts as a timeout, and an ICMP port
+	// unreachable, reported as a refused read, means nothing is listening
+	kind := model.NetErrorKind(err, model.ErrTimeout)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		kind = model.ErrDial
+	}
+	return model.Errorf(kind, "failed to send chunk %d/%d after %d tries: %w", chunk+1, t.total, t.sends[chunk], err)
+}
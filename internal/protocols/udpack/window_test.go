@@ -0,0 +1,95 @@
+package udp
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestTransfer returns a transfer of total chunks that have all been
+// sent once.
+func newTestTransfer(seq uint64, total int) *transfer {
+	t := newTransfer(NewClient("127.0.0.1:0"), seq, make([]byte, total*maxChunkSize))
+	sentAt := time.Now()
+	for i := range t.sends {
+		t.sends[i] = 1
+		t.sentAt[i] = sentAt
+	}
+	t.next = total
+	return t
+}
+
+// TestAckRoundTrip encodes acks as the server does and applies them as the
+// client does, which must leave exactly the chunks the server holds acked.
+func TestAckRoundTrip(t *testing.T) {
+	sackCap := maxChunkSize * 8
+	tests := []struct {
+		name     string
+		total    int
+		received []int
+		ackSeq   uint64 // the message the ack is for, when not the transfer's
+		want     []int  // acked chunks
+		bitmap   int    // expected bitmap length in bytes
+	}{
+		{name: "in order", total: 5, received: []int{0, 1, 2}, want: []int{0, 1, 2}},
+		{name: "gaps", total: 6, received: []int{0, 2, 4}, want: []int{0, 2, 4}, bitmap: 1},
+		{name: "nothing cumulative", total: 4, received: []int{3}, want: []int{3}, bitmap: 1},
+		{name: "bitmap over one byte", total: 20, received: []int{0, 3, 9, 12, 17}, want: []int{0, 3, 9, 12, 17}, bitmap: 2},
+		{
+			name:     "capped bitmap",
+			total:    sackCap + 10,
+			received: []int{1, sackCap, sackCap + 5},
+			want:     []int{1, sackCap},
+			bitmap:   maxChunkSize,
+		},
+		{name: "finished", total: 3, received: []int{2, 0, 1}, want: []int{0, 1, 2}},
+		{name: "stale seq", total: 4, received: []int{0, 2}, ackSeq: 6, bitmap: 1},
+	}
+
+	for _, tt := range tests {
+		const seq = 7
+		ackSeq := uint64(seq)
+		if tt.ackSeq != 0 {
+			ackSeq = tt.ackSeq
+		}
+
+		s := NewServer(":0")
+		var assembler *messageAssembler
+		for _, c := range tt.received {
+			assembler = s.handleChunk(testSender, dataHeader(ackSeq, c, tt.total), []byte{byte(c)}, time.Now())
+		}
+		bitmap := assembler.sack(nil)
+		if len(bitmap) != tt.bitmap {
+			t.Errorf("%s: bitmap of %d bytes, want %d", tt.name, len(bitmap), tt.bitmap)
+		}
+		ack := EncodeDatagram(nil, Header{Type: PacketAck, Seq: ackSeq, Chunk: uint32(assembler.next), Total: uint32(tt.total)}, bitmap)
+
+		tr := newTestTransfer(seq, tt.total)
+		tr.handleAck(ack)
+
+		want := make(map[int]bool)
+		for _, c := range tt.want {
+			want[c] = true
+		}
+		for c, acked := range tr.acked {
+			if acked != want[c] {
+				t.Errorf("%s: chunk %d acked = %v, want %v", tt.name, c, acked, want[c])
+			}
+		}
+		wantBase := 0
+		for wantBase < tt.total && want[wantBase] {
+			wantBase++
+		}
+		if tr.base != wantBase {
+			t.Errorf("%s: base = %d, want %d", tt.name, tr.base, wantBase)
+		}
+	}
+}
+
+func TestHandleAckIgnoresUnsentChunks(t *testing.T) {
+	tr := newTestTransfer(1, 4)
+	tr.sends[3] = 0
+	tr.handleAck(EncodeDatagram(nil, Header{Type: PacketAck, Seq: 1, Chunk: 4, Total: 4}, nil))
+	if tr.acked[3] || tr.base != 3 {
+		t.Errorf("unsent chunk acked: acked %v, base %d", tr.acked, tr.base)
+	}
+}
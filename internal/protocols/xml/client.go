@@ -2,11 +2,11 @@ package xml
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 
 	"protobench/internal/model"
 )
@@ -14,47 +14,56 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
-	port       string
-	server     *Server
 }
 
-func NewClient(port string) *Client {
+func NewClient(addr string) *Client {
 	return &Client{
-		baseURL:    fmt.Sprintf("http://localhost:%s", port),
-		httpClient: &http.Client{Timeout: time.Second},
-		port:       port,
-		server:     NewServer(port),
+		baseURL: "http://" + addr,
+		httpClient: &http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		},
 	}
 }
 
-func (c *Client) StartServer() error {
-	return c.server.Start()
-}
-
-func (c *Client) StopServer() error {
-	return c.server.Stop()
-}
-
-func (c *Client) Name() string {
-	return "XML"
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
 }
 
-func (c *Client) SendMessage(msg *model.Message) error {
+func (c *Client) Send(ctx context.Context, msg *model.Message) error {
 	data, err := xml.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(c.baseURL+"/message", "application/xml", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/message", bytes.NewReader(data))
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return model.Errorf(model.NetErrorKind(err, model.ErrWrite), "failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned error: %s - %s", resp.Status, string(body))
+		return model.Errorf(statusKind(resp.StatusCode), "server returned error: %s - %s", resp.Status, bytes.TrimSpace(body))
 	}
 
 	return nil
 }
+
+// statusKind maps the server's response status to an error kind.
+func statusKind(code int) model.ErrorKind {
+	switch code {
+	case http.StatusBadRequest:
+		return model.ErrDecode
+	case http.StatusUnprocessableEntity:
+		return model.ErrChecksum
+	default:
+		return model.ErrRejected
+	}
+}
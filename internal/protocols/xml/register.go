@@ -0,0 +1,21 @@
+package xml
+
+import (
+	"protobench/internal/model"
+	"protobench/internal/registry"
+)
+
+func init() {
+	registry.Register(registry.Entry{
+		Name:         "XML",
+		Description:  "XML over HTTP/1.1 POST",
+		DefaultPort:  "8085",
+		Capabilities: registry.Acked | registry.Connected | registry.Checksummed,
+		NewServer: func(addr string, _ registry.Options) (model.Server, error) {
+			return NewServer(addr), nil
+		},
+		NewClient: func(addr string, _ registry.Options) (model.Client, error) {
+			return NewClient(addr), nil
+		},
+	})
+}
@@ -3,33 +3,48 @@ package xml
 import (
 	"context"
 	"encoding/xml"
+	"fmt"
 	"net/http"
 	"time"
 
 	"protobench/internal/model"
+	"protobench/internal/wire"
 )
 
 type Server struct {
 	server *http.Server
-	port   string
+	addr   string
+	ledger *model.Ledger
 }
 
-func NewServer(port string) *Server {
+func NewServer(addr string) *Server {
 	return &Server{
-		port: port,
+		addr:   addr,
+		ledger: model.NewLedger(),
 	}
 }
 
+func (s *Server) Ledger() *model.Ledger {
+	return s.ledger
+}
+
+func (s *Server) Addr() string {
+	return s.addr
+}
+
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/message", s.handleMessage)
 
-	s.server = &http.Server{
-		Addr:    ":" + s.port,
-		Handler: mux,
+	listener, err := wire.Listen("tcp", s.addr, s.ledger.Traffic())
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
 	}
+	s.addr = model.DialAddr(listener.Addr())
 
-	go s.server.ListenAndServe()
+	s.server = &http.Server{Handler: mux}
+
+	go s.server.Serve(listener)
 	return nil
 }
 
@@ -53,6 +68,12 @@ func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	intact := msg.Verify()
+	s.ledger.Record(msg.Number, intact)
+	if !intact {
+		http.Error(w, "checksum mismatch", http.StatusUnprocessableEntity)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,178 @@
+// Package registry lets protocol packages plug themselves into the benchmark.
+// Each package registers an Entry from its init function; importing the
+// package for its side effects is enough to make the protocol available.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"protobench/internal/model"
+)
+
+// Capability describes a delivery property of a protocol.
+type Capability uint
+
+const (
+	// Acked means every message is acknowledged by the server before the
+	// send returns.
+	Acked Capability = 1 << iota
+	// Connected means messages travel over a persistent connection.
+	Connected
+	// Checksummed means the server verifies the payload checksum, so the
+	// corrupted count is meaningful.
+	Checksummed
+)
+
+var capabilityNames = []struct {
+	c    Capability
+	name string
+}{
+	{Acked, "acked"},
+	{Connected, "connected"},
+	{Checksummed, "checksummed"},
+}
+
+func (c Capability) String() string {
+	var names []string
+	for _, n := range capabilityNames {
+		if c&n.c != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ",")
+}
+
+// Option is a protocol-specific setting with its default value.
+type Option struct {
+	Name    string
+	Default string
+	Usage   string
+}
+
+// Options holds resolved option values by name.
+type Options map[string]string
+
+func (o Options) Int(name string) (int, error) {
+	n, err := strconv.Atoi(o[name])
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %q is not an integer", name, o[name])
+	}
+	return n, nil
+}
+
+func (o Options) Duration(name string) (time.Duration, error) {
+	d, err := time.ParseDuration(o[name])
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %q is not a duration", name, o[name])
+	}
+	return d, nil
+}
+
+func (o Options) Float(name string) (float64, error) {
+	f, err := strconv.ParseFloat(o[name], 64)
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %q is not a number", name, o[name])
+	}
+	return f, nil
+}
+
+// Entry describes a registered protocol.
+type Entry struct {
+	Name         string
+	Description  string
+	DefaultPort  string
+	Capabilities Capability
+	Options      []Option
+	// Extra protocols only run when selected explicitly, keeping the
+	// default run to the primary protocols.
+	Extra bool
+	// NewServer returns a server that will listen on addr, and NewClient a
+	// client that dials addr. Both fail when an option value is invalid.
+	NewServer func(addr string, opts Options) (model.Server, error)
+	NewClient func(addr string, opts Options) (model.Client, error)
+}
+
+// Resolve merges overrides into the entry's option defaults, rejecting
+// options the protocol does not define.
+func (e Entry) Resolve(overrides map[string]string) (Options, error) {
+	opts := make(Options, len(e.Options))
+	for _, o := range e.Options {
+		opts[o.Name] = o.Default
+	}
+	for name, value := range overrides {
+		if _, ok := opts[name]; !ok {
+			return nil, fmt.Errorf("%s has no option %q", e.Name, name)
+		}
+		opts[name] = value
+	}
+	return opts, nil
+}
+
+var (
+	mu      sync.Mutex
+	entries = make(map[string]Entry)
+)
+
+// Register makes a protocol available by name. It panics if the name is
+// already registered or the entry lacks a factory.
+func Register(e Entry) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	key := strings.ToLower(e.Name)
+	if e.NewServer == nil || e.NewClient == nil {
+		panic("registry: Register " + e.Name + " without a factory")
+	}
+	if _, dup := entries[key]; dup {
+		panic("registry: Register called twice for " + e.Name)
+	}
+	entries[key] = e
+}
+
+// Lookup finds a protocol by case-insensitive name.
+func Lookup(name string) (Entry, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	e, ok := entries[strings.ToLower(name)]
+	return e, ok
+}
+
+// All returns every registered protocol ordered by default port, which
+// keeps the order stable regardless of package initialisation order.
+func All() []Entry {
+	mu.Lock()
+	defer mu.Unlock()
+
+	all := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		all = append(all, e)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		pi, _ := strconv.Atoi(all[i].DefaultPort)
+		pj, _ := strconv.Atoi(all[j].DefaultPort)
+		if pi != pj {
+			return pi < pj
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// Names returns the names of every registered protocol.
+func Names() []string {
+	all := All()
+	names := make([]string, len(all))
+	for i, e := range all {
+		names[i] = e.Name
+	}
+	return names
+}
@@ -0,0 +1,210 @@
+// Package remote runs protocol servers in one process and drives them from
+// another, possibly on a different host. The serving side exposes a small
+// HTTP control endpoint through which the driving side discovers the
+// servers, resets their ledgers before each measurement and reads the
+// server-side delivery counts afterwards.
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"protobench/internal/model"
+)
+
+const DefaultControlPort = "7070"
+
+// ServerInfo describes one server exposed by a Controller.
+type ServerInfo struct {
+	Protocol string `json:"protocol"`
+	Port     string `json:"port"`
+}
+
+// Controller serves the control endpoint for a set of running servers.
+type Controller struct {
+	mu       sync.Mutex
+	servers  map[string]model.Server
+	infos    []ServerInfo
+	server   *http.Server
+	shutdown chan struct{}
+	once     sync.Once
+}
+
+func NewController() *Controller {
+	return &Controller{
+		servers:  make(map[string]model.Server),
+		shutdown: make(chan struct{}),
+	}
+}
+
+// Add exposes a started server under its protocol name.
+func (c *Controller) Add(protocol string, server model.Server) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, port, _ := net.SplitHostPort(server.Addr())
+	c.servers[strings.ToLower(protocol)] = server
+	c.infos = append(c.infos, ServerInfo{Protocol: protocol, Port: port})
+}
+
+// Start listens on addr and serves the control endpoint until Stop.
+func (c *Controller) Start(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /servers", c.handleServers)
+	mux.HandleFunc("GET /ledger/{protocol}", c.handleLedger)
+	mux.HandleFunc("POST /ledger/{protocol}/reset", c.handleReset)
+	mux.HandleFunc("POST /shutdown", c.handleShutdown)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	c.server = &http.Server{Handler: mux}
+	go c.server.Serve(listener)
+	return nil
+}
+
+func (c *Controller) Stop() error {
+	if c.server != nil {
+		return c.server.Close()
+	}
+	return nil
+}
+
+// Done is closed when a client requests shutdown.
+func (c *Controller) Done() <-chan struct{} {
+	return c.shutdown
+}
+
+func (c *Controller) lookup(w http.ResponseWriter, r *http.Request) model.Server {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	server, ok := c.servers[strings.ToLower(r.PathValue("protocol"))]
+	if !ok {
+		http.Error(w, "unknown protocol", http.StatusNotFound)
+	}
+	return server
+}
+
+func (c *Controller) handleServers(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeJSON(w, c.infos)
+}
+
+func (c *Controller) handleLedger(w http.ResponseWriter, r *http.Request) {
+	if server := c.lookup(w, r); server != nil {
+		writeJSON(w, server.Ledger().Report())
+	}
+}
+
+func (c *Controller) handleReset(w http.ResponseWriter, r *http.Request) {
+	if server := c.lookup(w, r); server != nil {
+		server.Ledger().Reset()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (c *Controller) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+	c.once.Do(func() { close(c.shutdown) })
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// Client talks to a Controller.
+type Client struct {
+	baseURL    string
+	host       string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the controller at target, which is a host
+// or host:port; the port defaults to DefaultControlPort.
+func NewClient(target string) *Client {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		host, port = target, DefaultControlPort
+	}
+	return &Client{
+		baseURL:    "http://" + net.JoinHostPort(host, port),
+		host:       host,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Servers lists the servers the controller exposes.
+func (c *Client) Servers() ([]ServerInfo, error) {
+	var infos []ServerInfo
+	if err := c.do(http.MethodGet, "/servers", &infos); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// DialAddr returns the address a client should dial to reach info's server.
+func (c *Client) DialAddr(info ServerInfo) string {
+	return net.JoinHostPort(c.host, info.Port)
+}
+
+// Ledger returns a tracker for the remote server of protocol.
+func (c *Client) Ledger(protocol string) model.DeliveryTracker {
+	return &remoteLedger{client: c, protocol: protocol}
+}
+
+// Shutdown asks the serving process to exit.
+func (c *Client) Shutdown() error {
+	return c.do(http.MethodPost, "/shutdown", nil)
+}
+
+func (c *Client) do(method, path string, out any) error {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("control request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read control response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s - %s", method, path, resp.Status, bytes.TrimSpace(body))
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("failed to decode control response: %w", err)
+		}
+	}
+	return nil
+}
+
+type remoteLedger struct {
+	client   *Client
+	protocol string
+}
+
+func (l *remoteLedger) ResetDelivery() error {
+	return l.client.do(http.MethodPost, "/ledger/"+l.protocol+"/reset", nil)
+}
+
+func (l *remoteLedger) Delivery() (model.DeliveryReport, error) {
+	var report model.DeliveryReport
+	err := l.client.do(http.MethodGet, "/ledger/"+l.protocol, &report)
+	return report, err
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"protobench/internal/benchmark"
+)
+
+// NewCodecs wraps serialization-only results in a report carrying the same
+// metadata as a network run.
+func NewCodecs(params benchmark.Options, results []benchmark.CodecResult) *Report {
+	r := New(params, 1, []benchmark.Result{})
+	r.Codecs = results
+	return r
+}
+
+// writeCodecTable renders codec results with each operation's cost side by
+// side.
+func writeCodecTable(w io.Writer, results []benchmark.CodecResult) error {
+	fmt.Fprintln(w, "\nSerialization (no network):")
+	fmt.Fprintf(w, "%-10s %7s %12s %15s %12s %12s %15s %12s %12s\n",
+		"Codec", "Size", "Encoded", "Marshal ns/op", "allocs/op", "B/op", "Unmarshal ns/op", "allocs/op", "B/op")
+	fmt.Fprintln(w, strings.Repeat("-", 117))
+
+	for _, res := range results {
+		_, err := fmt.Fprintf(w, "%-10s %7s %12d %15.0f %12d %12d %15.0f %12d %12d\n",
+			res.Codec,
+			fmt.Sprintf("%dKB", res.MessageSize),
+			res.EncodedBytes,
+			res.Marshal.NsPerOp,
+			res.Marshal.AllocsPerOp,
+			res.Marshal.BytesPerOp,
+			res.Unmarshal.NsPerOp,
+			res.Unmarshal.AllocsPerOp,
+			res.Unmarshal.BytesPerOp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var codecCSVHeader = []string{
+	"codec", "message_size_kb", "encoded_bytes",
+	"marshal_iterations", "marshal_ns_per_op", "marshal_allocs_per_op", "marshal_bytes_per_op",
+	"unmarshal_iterations", "unmarshal_ns_per_op", "unmarshal_allocs_per_op", "unmarshal_bytes_per_op",
+	"created_at", "hostname", "git_commit", "go_version", "gomaxprocs",
+}
+
+// writeCodecCSV writes one row per codec and size, repeating run metadata
+// on every row as WriteCSV does.
+func writeCodecCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(codecCSVHeader); err != nil {
+		return err
+	}
+
+	m := r.Metadata
+	for _, res := range r.Codecs {
+		row := []string{res.Codec, strconv.Itoa(res.MessageSize), strconv.Itoa(res.EncodedBytes)}
+		for _, op := range []benchmark.OpStats{res.Marshal, res.Unmarshal} {
+			row = append(row,
+				strconv.Itoa(op.Iterations),
+				strconv.FormatFloat(op.NsPerOp, 'f', 3, 64),
+				strconv.FormatInt(op.AllocsPerOp, 10),
+				strconv.FormatInt(op.BytesPerOp, 10),
+			)
+		}
+		row = append(row,
+			m.CreatedAt.Format(time.RFC3339),
+			m.Hostname,
+			m.GitCommit,
+			m.GoVersion,
+			strconv.Itoa(m.GOMAXPROCS),
+		)
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
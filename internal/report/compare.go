@@ -0,0 +1,149 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"protobench/internal/benchmark"
+)
+
+// Delta compares one metric of one protocol between a baseline and a
+// candidate report.
+type Delta struct {
+	Protocol    string
+	MessageSize int
+	Metric      string
+	Old         benchmark.Sample
+	New         benchmark.Sample
+	Change      float64 // relative change of the mean, (new-old)/old
+	PValue      float64 // NaN when either side has fewer than two runs
+	Significant bool
+	// Regression is set when the change is in the bad direction, exceeds
+	// the threshold, and is not shown to be noise: either the difference
+	// is significant or there are too few runs to test it.
+	Regression bool
+}
+
+type metric struct {
+	name           string
+	higherIsBetter bool
+	sample         func(benchmark.Summary) benchmark.Sample
+	format         func(float64) string
+}
+
+var compareMetrics = []metric{
+	{"msgs/sec", true, func(s benchmark.Summary) benchmark.Sample { return s.Throughput }, formatRate},
+	{"p50", false, func(s benchmark.Summary) benchmark.Sample { return s.P50 }, formatLatency},
+	{"p90", false, func(s benchmark.Summary) benchmark.Sample { return s.P90 }, formatLatency},
+	{"p99", false, func(s benchmark.Summary) benchmark.Sample { return s.P99 }, formatLatency},
+	{"p99.9", false, func(s benchmark.Summary) benchmark.Sample { return s.P999 }, formatLatency},
+}
+
+// Compare computes per-protocol deltas for throughput and latency
+// percentiles. threshold is the fractional change (0.05 for 5%) beyond
+// which a change in the bad direction counts as a regression. Protocol and
+// message size pairs present in only one report are skipped.
+func Compare(old, new *Report, threshold float64) []Delta {
+	type key struct {
+		protocol string
+		size     int
+	}
+	oldByKey := make(map[key]benchmark.Summary)
+	for _, s := range benchmark.Summarize(old.Results) {
+		oldByKey[key{s.Protocol, s.MessageSize}] = s
+	}
+
+	var deltas []Delta
+	for _, m := range compareMetrics {
+		for _, n := range benchmark.Summarize(new.Results) {
+			o, ok := oldByKey[key{n.Protocol, n.MessageSize}]
+			if !ok {
+				continue
+			}
+
+			d := Delta{
+				Protocol:    n.Protocol,
+				MessageSize: n.MessageSize,
+				Metric:      m.name,
+				Old:         m.sample(o),
+				New:         m.sample(n),
+			}
+			if d.Old.Mean != 0 {
+				d.Change = (d.New.Mean - d.Old.Mean) / d.Old.Mean
+			}
+			d.PValue = benchmark.WelchTTest(d.Old.Values, d.New.Values)
+			d.Significant = !math.IsNaN(d.PValue) && d.PValue < benchmark.Significance
+
+			worse := d.Change
+			if m.higherIsBetter {
+				worse = -d.Change
+			}
+			d.Regression = worse > threshold && (d.Significant || math.IsNaN(d.PValue))
+
+			deltas = append(deltas, d)
+		}
+	}
+	return deltas
+}
+
+// WriteComparison prints deltas in the style of benchstat, one block per
+// metric. Changes that are not significant are shown as "~".
+func WriteComparison(w io.Writer, oldName, newName string, deltas []Delta) {
+	for _, m := range compareMetrics {
+		var rows []Delta
+		for _, d := range deltas {
+			if d.Metric == m.name {
+				rows = append(rows, d)
+			}
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n%-18s %24s %24s   %s\n", m.name, oldName, newName, "delta")
+		fmt.Fprintln(w, strings.Repeat("-", 106))
+		for _, d := range rows {
+			fmt.Fprintf(w, "%-18s %24s %24s   %s\n",
+				fmt.Sprintf("%s %dKB", d.Protocol, d.MessageSize),
+				formatSample(d.Old, m.format),
+				formatSample(d.New, m.format),
+				formatChange(d))
+		}
+	}
+}
+
+func formatSample(s benchmark.Sample, format func(float64) string) string {
+	if s.Mean == 0 || len(s.Values) < 2 {
+		return format(s.Mean)
+	}
+	return fmt.Sprintf("%s ± %.0f%%", format(s.Mean), 100*s.Margin()/s.Mean)
+}
+
+func formatChange(d Delta) string {
+	n := fmt.Sprintf("n=%d+%d", len(d.Old.Values), len(d.New.Values))
+
+	var out string
+	switch {
+	case math.IsNaN(d.PValue):
+		out = fmt.Sprintf("%+.2f%% (%s, untested)", d.Change*100, n)
+	case !d.Significant:
+		out = fmt.Sprintf("~ (p=%.3f %s)", d.PValue, n)
+	default:
+		out = fmt.Sprintf("%+.2f%% (p=%.3f %s)", d.Change*100, d.PValue, n)
+	}
+	if d.Regression {
+		out += "  REGRESSION"
+	}
+	return out
+}
+
+func formatRate(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+func formatLatency(v float64) string {
+	return time.Duration(v).Round(time.Microsecond).String()
+}
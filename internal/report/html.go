@@ -0,0 +1,493 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"protobench/internal/benchmark"
+)
+
+// Source is one result file fed into an HTML report.
+type Source struct {
+	Name   string
+	Report *Report
+}
+
+// WriteHTML renders a self-contained HTML page with inline SVG charts. It
+// references no external scripts, fonts or stylesheets so it can be opened
+// offline or attached to a ticket.
+func WriteHTML(w io.Writer, title string, sources []Source) error {
+	page := htmlPage{Title: title, Generated: time.Now().UTC().Format(time.RFC3339)}
+
+	labelled := len(sources) > 1
+	var all []series
+	for _, src := range sources {
+		page.Sources = append(page.Sources, newSourceRow(src))
+		for _, res := range src.Report.Results {
+			name := res.Protocol
+			if labelled {
+				name = src.Name + ": " + res.Protocol
+			}
+			all = append(all, series{name: name, result: res})
+		}
+	}
+
+	sizes := distinctSizes(all)
+	for _, size := range sizes {
+		var ofSize []series
+		for _, s := range all {
+			if s.result.MessageSize == size {
+				ofSize = append(ofSize, s)
+			}
+		}
+		page.Sizes = append(page.Sizes, sizeSection{
+			Label:      fmt.Sprintf("%dKB messages", size),
+			Throughput: throughputChart(ofSize),
+			CDF:        cdfChart(ofSize),
+		})
+	}
+	if len(sizes) > 1 {
+		page.SizeChart = sizeChart(all)
+	}
+
+	page.Rows = all
+	return htmlTemplate.Execute(w, page)
+}
+
+type series struct {
+	name   string
+	result benchmark.Result
+}
+
+func (s series) Name() string                   { return s.name }
+func (s series) Result() benchmark.Result       { return s.result }
+func (s series) Latency(d time.Duration) string { return d.Round(time.Microsecond).String() }
+
+// ErrorSummary lists the error kinds of the result with one sample each.
+func (s series) ErrorSummary() string {
+	parts := make([]string, len(s.result.ErrorBreakdown))
+	for i, b := range s.result.ErrorBreakdown {
+		parts[i] = fmt.Sprintf("%s %d", b.Kind, b.Count)
+		if len(b.Samples) > 0 {
+			parts[i] += ": " + b.Samples[0]
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+type sizeSection struct {
+	Label      string
+	Throughput template.HTML
+	CDF        template.HTML
+}
+
+// sourceRow is the metadata of one source, preformatted for the page.
+type sourceRow struct {
+	Name       string
+	Created    string
+	Host       string
+	Commit     string
+	GoVersion  string
+	GOMAXPROCS int
+	Runs       int
+	Workload   string
+	Workers    int
+	Rate       string
+}
+
+func newSourceRow(src Source) sourceRow {
+	m := src.Report.Metadata
+	p := m.Parameters
+	row := sourceRow{
+		Name:       src.Name,
+		Host:       m.Hostname,
+		Commit:     shorten(m.GitCommit, 13),
+		GoVersion:  m.GoVersion,
+		GOMAXPROCS: m.GOMAXPROCS,
+		Runs:       m.Runs,
+		Workload:   fmt.Sprintf("%d messages", p.MessageCount),
+		Workers:    p.Concurrency,
+		Rate:       "closed loop",
+	}
+	if !m.CreatedAt.IsZero() {
+		row.Created = m.CreatedAt.Format("2006-01-02 15:04:05 MST")
+	}
+	if p.Duration > 0 {
+		row.Workload = p.Duration.String()
+	}
+	if p.Rate > 0 {
+		row.Rate = fmt.Sprintf("%.0f msgs/sec", p.Rate)
+	}
+	return row
+}
+
+type htmlPage struct {
+	Title     string
+	Generated string
+	Sources   []sourceRow
+	Sizes     []sizeSection
+	SizeChart template.HTML
+	Rows      []series
+}
+
+func distinctSizes(all []series) []int {
+	seen := make(map[int]bool)
+	var sizes []int
+	for _, s := range all {
+		if !seen[s.result.MessageSize] {
+			seen[s.result.MessageSize] = true
+			sizes = append(sizes, s.result.MessageSize)
+		}
+	}
+	sort.Ints(sizes)
+	return sizes
+}
+
+// groupByName collects repeated runs of the same series, keeping order.
+func groupByName(all []series) ([]string, map[string][]benchmark.Result) {
+	var order []string
+	grouped := make(map[string][]benchmark.Result)
+	for _, s := range all {
+		if _, ok := grouped[s.name]; !ok {
+			order = append(order, s.name)
+		}
+		grouped[s.name] = append(grouped[s.name], s.result)
+	}
+	return order, grouped
+}
+
+var palette = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+	"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+}
+
+const (
+	chartWidth   = 720
+	chartHeight  = 320
+	marginLeft   = 80
+	marginRight  = 160
+	marginTop    = 20
+	marginBottom = 50
+	plotWidth    = chartWidth - marginLeft - marginRight
+	plotHeight   = chartHeight - marginTop - marginBottom
+)
+
+// throughputChart draws the mean msgs/sec of each series as a bar, with a
+// whisker for the 95% confidence interval when there were repeated runs.
+func throughputChart(all []series) template.HTML {
+	order, grouped := groupByName(all)
+	samples := make([]benchmark.Sample, len(order))
+	top := 0.0
+	for i, name := range order {
+		values := make([]float64, len(grouped[name]))
+		for j, r := range grouped[name] {
+			values[j] = r.MessagesPerSecond
+		}
+		samples[i] = benchmark.NewSample(values)
+		top = math.Max(top, samples[i].CIHigh)
+	}
+	ticks := niceTicks(0, top, 5)
+	yMax := ticks[len(ticks)-1]
+
+	var b strings.Builder
+	openSVG(&b, "Throughput (msgs/sec)")
+	for _, t := range ticks {
+		y := marginTop + plotHeight - plotHeight*t/yMax
+		fmt.Fprintf(&b, `<line class="grid" x1="%d" y1="%.1f" x2="%d" y2="%.1f"/>`, marginLeft, y, marginLeft+plotWidth, y)
+		fmt.Fprintf(&b, `<text class="tick" x="%d" y="%.1f" text-anchor="end">%s</text>`, marginLeft-6, y+4, formatRate(t))
+	}
+
+	slot := float64(plotWidth) / float64(max(len(order), 1))
+	for i, name := range order {
+		s := samples[i]
+		x := marginLeft + slot*float64(i) + slot*0.15
+		h := plotHeight * s.Mean / yMax
+		color := palette[i%len(palette)]
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s msgs/sec</title></rect>`,
+			x, marginTop+plotHeight-h, slot*0.7, h, color, template.HTMLEscapeString(name), formatRate(s.Mean))
+		if len(s.Values) > 1 {
+			cx := x + slot*0.35
+			y1 := marginTop + plotHeight - plotHeight*s.CILow/yMax
+			y2 := marginTop + plotHeight - plotHeight*s.CIHigh/yMax
+			fmt.Fprintf(&b, `<line class="whisker" x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"/>`, cx, y1, cx, y2)
+		}
+		fmt.Fprintf(&b, `<text class="tick" x="%.1f" y="%d" text-anchor="middle">%s</text>`,
+			x+slot*0.35, marginTop+plotHeight+16, template.HTMLEscapeString(shorten(name, 18)))
+	}
+	closeSVG(&b)
+	return template.HTML(b.String())
+}
+
+// cdfChart plots latency on a log axis against cumulative percentile. Runs
+// of the same series are averaged point by point.
+func cdfChart(all []series) template.HTML {
+	order, grouped := groupByName(all)
+	var lines []line
+	for i, name := range order {
+		var points []point
+		runs := grouped[name]
+		for q := range runs[0].LatencyCDF {
+			var sum float64
+			n := 0
+			for _, r := range runs {
+				if q < len(r.LatencyCDF) {
+					sum += float64(r.LatencyCDF[q].Value)
+					n++
+				}
+			}
+			points = append(points, point{x: sum / float64(n), y: runs[0].LatencyCDF[q].Percentile})
+		}
+		if len(points) > 0 {
+			lines = append(lines, line{name: name, color: palette[i%len(palette)], points: points})
+		}
+	}
+	if len(lines) == 0 {
+		return template.HTML(`<p class="note">No latency distribution in these results (CSV exports carry summary percentiles only).</p>`)
+	}
+	return lineChart("Latency CDF", lines, true, formatLatency, func(v float64) string { return fmt.Sprintf("%g%%", v) }, 0, 100)
+}
+
+// sizeChart plots mean throughput against message size for each protocol.
+func sizeChart(all []series) template.HTML {
+	type key struct {
+		name string
+		size int
+	}
+	order, _ := groupByName(all)
+	sums := make(map[key][]float64)
+	for _, s := range all {
+		k := key{s.name, s.result.MessageSize}
+		sums[k] = append(sums[k], s.result.MessagesPerSecond)
+	}
+
+	sizes := distinctSizes(all)
+	var lines []line
+	top := 0.0
+	for i, name := range order {
+		var points []point
+		for _, size := range sizes {
+			if values, ok := sums[key{name, size}]; ok {
+				mean := benchmark.NewSample(values).Mean
+				points = append(points, point{x: float64(size), y: mean})
+				top = math.Max(top, mean)
+			}
+		}
+		lines = append(lines, line{name: name, color: palette[i%len(palette)], points: points})
+	}
+	return lineChart("Throughput vs message size", lines, true,
+		func(v float64) string { return fmt.Sprintf("%gKB", v) }, formatRate, 0, top)
+}
+
+type point struct{ x, y float64 }
+
+type line struct {
+	name   string
+	color  string
+	points []point
+}
+
+func lineChart(title string, lines []line, logX bool, xFormat, yFormat func(float64) string, yMin, yMax float64) template.HTML {
+	xMin, xMax := math.Inf(1), math.Inf(-1)
+	for _, l := range lines {
+		for _, p := range l.points {
+			if logX && p.x <= 0 {
+				continue
+			}
+			xMin, xMax = math.Min(xMin, p.x), math.Max(xMax, p.x)
+		}
+	}
+	if math.IsInf(xMin, 0) {
+		return ""
+	}
+
+	var xTicks []float64
+	if logX {
+		xTicks = logTicks(xMin, xMax)
+		xMin, xMax = math.Min(xMin, xTicks[0]), math.Max(xMax, xTicks[len(xTicks)-1])
+	} else {
+		xTicks = niceTicks(xMin, xMax, 6)
+		xMin, xMax = xTicks[0], xTicks[len(xTicks)-1]
+	}
+	yTicks := niceTicks(yMin, yMax, 5)
+	yMin, yMax = yTicks[0], yTicks[len(yTicks)-1]
+
+	scaleX := func(v float64) float64 {
+		if logX {
+			if xMax == xMin {
+				return marginLeft + plotWidth/2
+			}
+			return marginLeft + plotWidth*(math.Log10(v)-math.Log10(xMin))/(math.Log10(xMax)-math.Log10(xMin))
+		}
+		if xMax == xMin {
+			return marginLeft + plotWidth/2
+		}
+		return marginLeft + plotWidth*(v-xMin)/(xMax-xMin)
+	}
+	scaleY := func(v float64) float64 {
+		if yMax == yMin {
+			return marginTop + plotHeight/2
+		}
+		return marginTop + plotHeight - plotHeight*(v-yMin)/(yMax-yMin)
+	}
+
+	var b strings.Builder
+	openSVG(&b, title)
+	for _, t := range yTicks {
+		y := scaleY(t)
+		fmt.Fprintf(&b, `<line class="grid" x1="%d" y1="%.1f" x2="%d" y2="%.1f"/>`, marginLeft, y, marginLeft+plotWidth, y)
+		fmt.Fprintf(&b, `<text class="tick" x="%d" y="%.1f" text-anchor="end">%s</text>`, marginLeft-6, y+4, yFormat(t))
+	}
+	for _, t := range xTicks {
+		x := scaleX(t)
+		fmt.Fprintf(&b, `<line class="grid" x1="%.1f" y1="%d" x2="%.1f" y2="%d"/>`, x, marginTop, x, marginTop+plotHeight)
+		fmt.Fprintf(&b, `<text class="tick" x="%.1f" y="%d" text-anchor="middle">%s</text>`, x, marginTop+plotHeight+16, xFormat(t))
+	}
+
+	for i, l := range lines {
+		var path strings.Builder
+		for _, p := range l.points {
+			if logX && p.x <= 0 {
+				continue
+			}
+			cmd := "L"
+			if path.Len() == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(&path, "%s%.1f,%.1f ", cmd, scaleX(p.x), scaleY(p.y))
+		}
+		fmt.Fprintf(&b, `<path d="%s" fill="none" stroke="%s" stroke-width="2"><title>%s</title></path>`,
+			strings.TrimSpace(path.String()), l.color, template.HTMLEscapeString(l.name))
+		for _, p := range l.points {
+			if logX && p.x <= 0 {
+				continue
+			}
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2.5" fill="%s"><title>%s: %s, %s</title></circle>`,
+				scaleX(p.x), scaleY(p.y), l.color, template.HTMLEscapeString(l.name), xFormat(p.x), yFormat(p.y))
+		}
+
+		ly := marginTop + 14*i + 6
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, marginLeft+plotWidth+12, ly, l.color)
+		fmt.Fprintf(&b, `<text class="legend" x="%d" y="%d">%s</text>`, marginLeft+plotWidth+26, ly+9, template.HTMLEscapeString(shorten(l.name, 22)))
+	}
+	closeSVG(&b)
+	return template.HTML(b.String())
+}
+
+func openSVG(b *strings.Builder, title string) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img"><title>%s</title>`,
+		chartWidth, chartHeight, chartWidth, chartHeight, template.HTMLEscapeString(title))
+	fmt.Fprintf(b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, marginLeft, marginTop+plotHeight, marginLeft+plotWidth, marginTop+plotHeight)
+	fmt.Fprintf(b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, marginLeft, marginTop, marginLeft, marginTop+plotHeight)
+}
+
+func closeSVG(b *strings.Builder) {
+	b.WriteString(`</svg>`)
+}
+
+// niceTicks returns evenly spaced round values covering [lo, hi].
+func niceTicks(lo, hi float64, n int) []float64 {
+	if hi <= lo {
+		hi = lo + 1
+	}
+	raw := (hi - lo) / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := mag
+	for _, m := range []float64{1, 2, 5, 10} {
+		if m*mag >= raw {
+			step = m * mag
+			break
+		}
+	}
+
+	var ticks []float64
+	for v := math.Floor(lo/step) * step; v < hi+step/2; v += step {
+		ticks = append(ticks, v)
+	}
+	if ticks[len(ticks)-1] < hi {
+		ticks = append(ticks, ticks[len(ticks)-1]+step)
+	}
+	return ticks
+}
+
+// logTicks returns 1-2-5 steps per decade covering [lo, hi].
+func logTicks(lo, hi float64) []float64 {
+	var ticks []float64
+	for decade := math.Pow(10, math.Floor(math.Log10(lo))); decade <= hi*10; decade *= 10 {
+		for _, m := range []float64{1, 2, 5} {
+			v := decade * m
+			if v >= lo/2 && v <= hi*2 {
+				ticks = append(ticks, v)
+			}
+		}
+	}
+	if len(ticks) == 0 {
+		ticks = []float64{lo, hi}
+	}
+	return ticks
+}
+
+func shorten(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; } h2 { font-size: 1.2em; margin-top: 2em; } h3 { font-size: 1em; }
+table { border-collapse: collapse; font-size: 0.85em; margin: 1em 0; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th { background: #f4f4f4; }
+.charts { display: flex; flex-wrap: wrap; gap: 1em; }
+svg { background: #fff; border: 1px solid #eee; font-size: 11px; }
+svg .axis { stroke: #444; } svg .grid { stroke: #eee; } svg .whisker { stroke: #222; stroke-width: 2; }
+svg .tick, svg .legend { fill: #444; }
+.note { color: #777; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="note">Generated {{.Generated}}</p>
+
+<h2>Runs</h2>
+<table>
+<tr><th>Source</th><th>Created</th><th>Host</th><th>Commit</th><th>Go</th><th>GOMAXPROCS</th><th>Runs</th><th>Workload</th><th>Workers</th><th>Load</th></tr>
+{{range .Sources}}<tr><td>{{.Name}}</td><td>{{.Created}}</td><td>{{.Host}}</td><td>{{.Commit}}</td><td>{{.GoVersion}}</td><td>{{.GOMAXPROCS}}</td><td>{{.Runs}}</td><td>{{.Workload}}</td><td>{{.Workers}}</td><td>{{.Rate}}</td></tr>
+{{end}}
+</table>
+
+{{range .Sizes}}
+<h2>{{.Label}}</h2>
+<div class="charts">
+{{.Throughput}}
+{{.CDF}}
+</div>
+{{end}}
+
+{{if .SizeChart}}
+<h2>Throughput vs message size</h2>
+{{.SizeChart}}
+{{end}}
+
+<h2>All results</h2>
+<table>
+<tr><th>Series</th><th>Run</th><th>Size</th><th>Msgs/sec</th><th>Errors</th><th>Timeouts</th><th>Missing</th><th>Bytes/msg</th><th>Overhead</th><th>P50</th><th>P90</th><th>P99</th><th>P99.9</th><th>Max</th></tr>
+{{range .Rows}}{{$r := .Result}}<tr><td>{{.Name}}</td><td>{{$r.Run}}</td><td>{{$r.MessageSize}}KB</td><td>{{printf "%.2f" $r.MessagesPerSecond}}</td><td title="{{.ErrorSummary}}">{{$r.Errors}}</td><td>{{$r.Timeouts}}</td><td>{{$r.Missing}}</td><td>{{printf "%.0f" $r.BytesPerMessage}}</td><td>{{printf "%.2fx" $r.OverheadRatio}}</td><td>{{.Latency $r.Latency.P50}}</td><td>{{.Latency $r.Latency.P90}}</td><td>{{.Latency $r.Latency.P99}}</td><td>{{.Latency $r.Latency.P999}}</td><td>{{.Latency $r.Latency.Max}}</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
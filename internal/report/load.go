@@ -0,0 +1,261 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"protobench/internal/benchmark"
+	"protobench/internal/model"
+)
+
+// Load reads a result file written by Write. The format is chosen by
+// extension: .csv files are parsed as CSV, everything else as JSON.
+func Load(path string) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r *Report
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		r, err = ReadCSV(f)
+	} else {
+		r, err = ReadJSON(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+func ReadJSON(rd io.Reader) (*Report, error) {
+	var r Report
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(r.SchemaVersion); err != nil {
+		return nil, err
+	}
+	for i := range r.Results {
+		if r.Results[i].MessageSize == 0 {
+			r.Results[i].MessageSize = r.Metadata.Parameters.MessageSize
+		}
+	}
+	return &r, nil
+}
+
+// checkSchemaVersion refuses files this version cannot read faithfully.
+// Version 1 counted timeouts among errors, so comparing it with later files
+// would compare different quantities.
+func checkSchemaVersion(version int) error {
+	if version > SchemaVersion {
+		return fmt.Errorf("schema version %d is newer than supported version %d", version, SchemaVersion)
+	}
+	if version < 2 {
+		return fmt.Errorf("schema version %d counted timeouts among errors and is no longer supported; rerun the benchmark to regenerate it", version)
+	}
+	return nil
+}
+
+// ReadCSV parses rows written by WriteCSV. Metadata is taken from the first
+// row; columns are matched by header name so their order does not matter.
+func ReadCSV(rd io.Reader) (*Report, error) {
+	rows, err := csv.NewReader(rd).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty CSV file")
+	}
+
+	col := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		col[name] = i
+	}
+	for _, name := range []string{"protocol", "messages_per_second"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	r := &Report{SchemaVersion: SchemaVersion}
+	for line, row := range rows[1:] {
+		p := csvRow{row: row, col: col}
+		// Files from before the column existed are version 1
+		if err := checkSchemaVersion(max(p.int("schema_version"), 1)); err != nil {
+			return nil, fmt.Errorf("row %d: %w", line+2, err)
+		}
+		res := benchmark.Result{
+			Protocol:          p.str("protocol"),
+			Run:               p.int("run"),
+			MessageSize:       p.int("message_size_kb"),
+			TotalTime:         p.dur("total_time_ns"),
+			Messages:          p.int("messages"),
+			MessagesPerSecond: p.float("messages_per_second"),
+			TargetRate:        p.float("target_rate"),
+			Errors:            p.int("errors"),
+			Timeouts:          p.int("timeouts"),
+			Missing:           p.int("missing"),
+			Duplicates:        p.int("duplicates"),
+			OutOfOrder:        p.int("out_of_order"),
+			Corrupted:         p.int("corrupted"),
+			Partial:           p.int("partial"),
+			Abandoned:         p.int("abandoned"),
+			Latency: benchmark.LatencyStats{
+				Min:    p.dur("latency_min_ns"),
+				Mean:   p.dur("latency_mean_ns"),
+				P50:    p.dur("latency_p50_ns"),
+				P90:    p.dur("latency_p90_ns"),
+				P99:    p.dur("latency_p99_ns"),
+				P999:   p.dur("latency_p999_ns"),
+				Max:    p.dur("latency_max_ns"),
+				StdDev: p.dur("latency_stddev_ns"),
+			},
+			BytesSent:       p.int64("bytes_sent"),
+			BytesReceived:   p.int64("bytes_received"),
+			BytesPerMessage: p.float("bytes_per_message"),
+			OverheadRatio:   p.float("overhead_ratio"),
+			WireReads:       p.int64("wire_reads"),
+			WireWrites:      p.int64("wire_writes"),
+		}
+		res.ErrorBreakdown = p.errorBreakdown()
+		res.ProtocolStats = p.protocolStats()
+		if p.err != nil {
+			return nil, fmt.Errorf("row %d: %w", line+2, p.err)
+		}
+
+		if line == 0 {
+			created, _ := time.Parse(time.RFC3339, p.str("created_at"))
+			r.Metadata = Metadata{
+				CreatedAt:  created,
+				Hostname:   p.str("hostname"),
+				GitCommit:  p.str("git_commit"),
+				GoVersion:  p.str("go_version"),
+				GOOS:       p.str("goos"),
+				GOARCH:     p.str("goarch"),
+				GOMAXPROCS: p.int("gomaxprocs"),
+				NumCPU:     p.int("num_cpu"),
+				Parameters: benchmark.Options{
+					MessageCount:   p.int("message_count"),
+					MessageSize:    p.int("message_size_kb"),
+					Concurrency:    p.int("concurrency"),
+					Duration:       p.dur("duration_ns"),
+					WarmupCount:    p.int("warmup_count"),
+					WarmupDuration: p.dur("warmup_duration_ns"),
+					Rate:           p.float("rate"),
+					Timeout:        p.dur("timeout_ns"),
+				},
+			}
+			if p.err != nil {
+				return nil, fmt.Errorf("row %d: %w", line+2, p.err)
+			}
+		}
+		r.Metadata.Runs = max(r.Metadata.Runs, res.Run)
+		r.Results = append(r.Results, res)
+	}
+	return r, nil
+}
+
+// csvRow reads typed cells by column name, remembering the first parse
+// error. Absent columns read as zero values.
+type csvRow struct {
+	row []string
+	col map[string]int
+	err error
+}
+
+func (p *csvRow) str(name string) string {
+	i, ok := p.col[name]
+	if !ok || i >= len(p.row) {
+		return ""
+	}
+	return p.row[i]
+}
+
+func (p *csvRow) int(name string) int {
+	s := p.str(name)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("column %s: %w", name, err)
+	}
+	return v
+}
+
+func (p *csvRow) float(name string) float64 {
+	s := p.str(name)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("column %s: %w", name, err)
+	}
+	return v
+}
+
+func (p *csvRow) int64(name string) int64 {
+	s := p.str(name)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("column %s: %w", name, err)
+	}
+	return v
+}
+
+func (p *csvRow) dur(name string) time.Duration {
+	return time.Duration(p.int64(name))
+}
+
+// errorBreakdown rebuilds Result.ErrorBreakdown from the per-kind columns
+// and the samples column written by WriteCSV.
+func (p *csvRow) errorBreakdown() []benchmark.ErrorBucket {
+	var buckets []benchmark.ErrorBucket
+	index := make(map[string]int)
+	for k := model.ErrorKind(0); k < model.NumErrorKinds; k++ {
+		if n := p.int("errors_" + k.String()); n > 0 {
+			index[k.String()] = len(buckets)
+			buckets = append(buckets, benchmark.ErrorBucket{Kind: k.String(), Count: n})
+		}
+	}
+	if samples := p.str("error_samples"); samples != "" {
+		for _, s := range strings.Split(samples, csvSampleSep) {
+			kind, msg, _ := strings.Cut(s, ": ")
+			if i, ok := index[kind]; ok {
+				buckets[i].Samples = append(buckets[i].Samples, msg)
+			}
+		}
+	}
+	sort.SliceStable(buckets, func(i, j int) bool { return buckets[i].Count > buckets[j].Count })
+	return buckets
+}
+
+// protocolStats decodes the protocol_stats column written by WriteCSV.
+func (p *csvRow) protocolStats() *model.ProtocolStats {
+	s := p.str("protocol_stats")
+	if s == "" {
+		return nil
+	}
+	var stats model.ProtocolStats
+	if err := json.Unmarshal([]byte(s), &stats); err != nil {
+		if p.err == nil {
+			p.err = fmt.Errorf("column protocol_stats: %w", err)
+		}
+		return nil
+	}
+	return &stats
+}
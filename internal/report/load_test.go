@@ -0,0 +1,94 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"protobench/internal/benchmark"
+	"protobench/internal/model"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	want := &Report{
+		SchemaVersion: SchemaVersion,
+		Metadata: Metadata{
+			CreatedAt:  time.Date(2026, 10, 17, 12, 30, 0, 0, time.UTC),
+			GoVersion:  "go1.22.5",
+			GOOS:       "linux",
+			GOARCH:     "arm64",
+			GOMAXPROCS: 8,
+			NumCPU:     16,
+			Hostname:   "bench-host",
+			GitCommit:  "0123456789abcdef",
+			Runs:       2,
+			Parameters: benchmark.Options{
+				MessageCount:   5000,
+				MessageSize:    16,
+				Concurrency:    4,
+				Duration:       3 * time.Second,
+				WarmupCount:    200,
+				WarmupDuration: 500 * time.Millisecond,
+				Rate:           1500.5,
+				Timeout:        2 * time.Second,
+			},
+		},
+		Results: []benchmark.Result{
+			{
+				Protocol:          "UDP-ACK",
+				Run:               1,
+				MessageSize:       16,
+				TotalTime:         3 * time.Second,
+				Messages:          4500,
+				MessagesPerSecond: 1500,
+				TargetRate:        1500.5,
+				Errors:            3,
+				Timeouts:          2,
+				Missing:           5,
+				Partial:           1,
+				Abandoned:         1,
+				Latency:           benchmark.LatencyStats{Min: time.Microsecond, P50: 2 * time.Millisecond, Max: time.Second},
+				ErrorBreakdown: []benchmark.ErrorBucket{
+					{Kind: model.ErrWrite.String(), Count: 3, Samples: []string{"broken pipe"}},
+				},
+				BytesSent:     1 << 30,
+				BytesReceived: 4096,
+				WireReads:     12,
+				WireWrites:    34,
+				ProtocolStats: &model.ProtocolStats{
+					Counters: map[string]int64{"retransmits": 7},
+					Gauges:   map[string]float64{"cwnd": 12.5},
+				},
+			},
+			{Protocol: "TCP", Run: 2, MessageSize: 16, Messages: 5000, MessagesPerSecond: 9000.25},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.Metadata.CreatedAt.Equal(want.Metadata.CreatedAt) {
+		t.Errorf("created_at = %v, want %v", got.Metadata.CreatedAt, want.Metadata.CreatedAt)
+	}
+	gotMeta, wantMeta := got.Metadata, want.Metadata
+	gotMeta.CreatedAt, wantMeta.CreatedAt = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(gotMeta, wantMeta) {
+		t.Errorf("metadata:\n got %+v\nwant %+v", gotMeta, wantMeta)
+	}
+
+	if len(got.Results) != len(want.Results) {
+		t.Fatalf("got %d results, want %d", len(got.Results), len(want.Results))
+	}
+	for i := range want.Results {
+		if !reflect.DeepEqual(got.Results[i], want.Results[i]) {
+			t.Errorf("result %d:\n got %+v\nwant %+v", i, got.Results[i], want.Results[i])
+		}
+	}
+}
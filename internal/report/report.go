@@ -0,0 +1,79 @@
+package report
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"protobench/internal/benchmark"
+)
+
+// SchemaVersion identifies the layout of exported result files and is bumped
+// whenever a field is renamed or removed or changes meaning. Version 2
+// stopped counting timeouts among errors.
+const SchemaVersion = 2
+
+// Report is the exported form of a benchmark invocation.
+type Report struct {
+	SchemaVersion int                `json:"schema_version"`
+	Metadata      Metadata           `json:"metadata"`
+	Results       []benchmark.Result `json:"results"`
+	Sweep         *Sweep             `json:"sweep,omitempty"`
+	// Codecs holds serialization-only results from the codec command.
+	Codecs []benchmark.CodecResult `json:"codecs,omitempty"`
+}
+
+// Metadata describes where and how the results were produced.
+type Metadata struct {
+	CreatedAt  time.Time         `json:"created_at"`
+	GoVersion  string            `json:"go_version"`
+	GOOS       string            `json:"goos"`
+	GOARCH     string            `json:"goarch"`
+	GOMAXPROCS int               `json:"gomaxprocs"`
+	NumCPU     int               `json:"num_cpu"`
+	Hostname   string            `json:"hostname"`
+	GitCommit  string            `json:"git_commit"`
+	Runs       int               `json:"runs"`
+	Parameters benchmark.Options `json:"parameters"`
+}
+
+func New(params benchmark.Options, runs int, results []benchmark.Result) *Report {
+	hostname, _ := os.Hostname()
+	return &Report{
+		SchemaVersion: SchemaVersion,
+		Metadata: Metadata{
+			CreatedAt:  time.Now().UTC(),
+			GoVersion:  runtime.Version(),
+			GOOS:       runtime.GOOS,
+			GOARCH:     runtime.GOARCH,
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+			NumCPU:     runtime.NumCPU(),
+			Hostname:   hostname,
+			GitCommit:  gitCommit(),
+			Runs:       runs,
+			Parameters: params,
+		},
+		Results: results,
+		Sweep:   NewSweep(results),
+	}
+}
+
+// gitCommit prefers the revision stamped by `go build` and falls back to
+// asking git, which covers `go run`.
+func gitCommit() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
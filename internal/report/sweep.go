@@ -0,0 +1,119 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"protobench/internal/benchmark"
+)
+
+// Sweep is the throughput matrix of a run across several message sizes.
+type Sweep struct {
+	Sizes      []int       `json:"sizes_kb"`
+	Protocols  []string    `json:"protocols"`
+	Throughput [][]float64 `json:"throughput"` // mean msgs/sec, indexed [size][protocol]
+	Crossovers []Crossover `json:"crossovers"`
+}
+
+// Crossover marks where one protocol overtakes another between two
+// consecutive sizes of a sweep.
+type Crossover struct {
+	FromSize  int    `json:"from_kb"`
+	ToSize    int    `json:"to_kb"`
+	Protocol  string `json:"protocol"`
+	Overtakes string `json:"overtakes"`
+}
+
+// NewSweep builds the throughput matrix from results, or returns nil when
+// they cover fewer than two message sizes.
+func NewSweep(results []benchmark.Result) *Sweep {
+	s := &Sweep{}
+	sizeIdx := make(map[int]int)
+	protoIdx := make(map[string]int)
+	summaries := benchmark.Summarize(results)
+	for _, sum := range summaries {
+		if _, ok := sizeIdx[sum.MessageSize]; !ok {
+			sizeIdx[sum.MessageSize] = 0
+			s.Sizes = append(s.Sizes, sum.MessageSize)
+		}
+		if _, ok := protoIdx[sum.Protocol]; !ok {
+			protoIdx[sum.Protocol] = len(s.Protocols)
+			s.Protocols = append(s.Protocols, sum.Protocol)
+		}
+	}
+	if len(s.Sizes) < 2 {
+		return nil
+	}
+
+	sort.Ints(s.Sizes)
+	for i, size := range s.Sizes {
+		sizeIdx[size] = i
+	}
+	s.Throughput = make([][]float64, len(s.Sizes))
+	for i := range s.Throughput {
+		s.Throughput[i] = make([]float64, len(s.Protocols))
+	}
+	for _, sum := range summaries {
+		s.Throughput[sizeIdx[sum.MessageSize]][protoIdx[sum.Protocol]] = sum.Throughput.Mean
+	}
+
+	for i := 0; i+1 < len(s.Sizes); i++ {
+		before, after := s.Throughput[i], s.Throughput[i+1]
+		for a := range s.Protocols {
+			for b := range s.Protocols {
+				if a != b && before[a] < before[b] && after[a] > after[b] {
+					s.Crossovers = append(s.Crossovers, Crossover{
+						FromSize:  s.Sizes[i],
+						ToSize:    s.Sizes[i+1],
+						Protocol:  s.Protocols[a],
+						Overtakes: s.Protocols[b],
+					})
+				}
+			}
+		}
+	}
+	return s
+}
+
+// WriteSweep prints the matrix with the fastest protocol at each size
+// starred, and flags the rows at which a crossover has happened.
+func WriteSweep(w io.Writer, s *Sweep) {
+	fmt.Fprintln(w, "\nThroughput by message size (msgs/sec, * = fastest):")
+	fmt.Fprintf(w, "%-8s", "Size")
+	for _, p := range s.Protocols {
+		fmt.Fprintf(w, " %13s", p)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, strings.Repeat("-", 8+14*len(s.Protocols)))
+
+	for i, size := range s.Sizes {
+		best := 0
+		for j, v := range s.Throughput[i] {
+			if v > s.Throughput[i][best] {
+				best = j
+			}
+		}
+
+		fmt.Fprintf(w, "%-8s", fmt.Sprintf("%dKB", size))
+		for j, v := range s.Throughput[i] {
+			cell := fmt.Sprintf("%.2f", v)
+			if j == best {
+				cell = "*" + cell
+			}
+			fmt.Fprintf(w, " %13s", cell)
+		}
+
+		var notes []string
+		for _, c := range s.Crossovers {
+			if c.ToSize == size {
+				notes = append(notes, fmt.Sprintf("%s overtakes %s", c.Protocol, c.Overtakes))
+			}
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(w, "  <- %s", strings.Join(notes, "; "))
+		}
+		fmt.Fprintln(w)
+	}
+}
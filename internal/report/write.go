@@ -0,0 +1,338 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"protobench/internal/benchmark"
+	"protobench/internal/model"
+)
+
+// Formats lists the names accepted by Write.
+var Formats = []string{"table", "json", "csv"}
+
+func Write(w io.Writer, format string, r *Report) error {
+	switch format {
+	case "table":
+		return WriteTable(w, r)
+	case "json":
+		return WriteJSON(w, r)
+	case "csv":
+		if len(r.Codecs) > 0 {
+			return writeCodecCSV(w, r)
+		}
+		return WriteCSV(w, r)
+	default:
+		return fmt.Errorf("unknown format %q (want one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+func WriteJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+var csvHeader = append([]string{
+	"protocol", "run", "total_time_ns", "messages", "messages_per_second", "target_rate",
+	"errors", "timeouts", "missing", "duplicates", "out_of_order", "corrupted",
+	"latency_min_ns", "latency_mean_ns", "latency_p50_ns", "latency_p90_ns",
+	"latency_p99_ns", "latency_p999_ns", "latency_max_ns", "latency_stddev_ns",
+	"message_size_kb", "concurrency", "created_at", "hostname", "git_commit",
+	"go_version", "gomaxprocs",
+	"bytes_sent", "bytes_received", "bytes_per_message", "overhead_ratio", "wire_reads", "wire_writes",
+	"partial", "abandoned", "protocol_stats", "schema_version",
+	"goos", "goarch", "num_cpu", "message_count", "duration_ns",
+	"warmup_count", "warmup_duration_ns", "rate", "timeout_ns",
+}, errorColumns()...)
+
+// errorColumns are the per-kind error counts, then the sample messages of
+// every kind joined with csvSampleSep.
+func errorColumns() []string {
+	cols := make([]string, 0, model.NumErrorKinds+1)
+	for k := model.ErrorKind(0); k < model.NumErrorKinds; k++ {
+		cols = append(cols, "errors_"+k.String())
+	}
+	return append(cols, "error_samples")
+}
+
+const csvSampleSep = " | "
+
+// WriteCSV writes one row per result. Run metadata is repeated on every row
+// so that rows from different files can be concatenated.
+func WriteCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	m := r.Metadata
+	for _, res := range r.Results {
+		row := []string{
+			res.Protocol,
+			strconv.Itoa(res.Run),
+			ns(res.TotalTime),
+			strconv.Itoa(res.Messages),
+			strconv.FormatFloat(res.MessagesPerSecond, 'f', 3, 64),
+			strconv.FormatFloat(res.TargetRate, 'f', 3, 64),
+			strconv.Itoa(res.Errors),
+			strconv.Itoa(res.Timeouts),
+			strconv.Itoa(res.Missing),
+			strconv.Itoa(res.Duplicates),
+			strconv.Itoa(res.OutOfOrder),
+			strconv.Itoa(res.Corrupted),
+			ns(res.Latency.Min),
+			ns(res.Latency.Mean),
+			ns(res.Latency.P50),
+			ns(res.Latency.P90),
+			ns(res.Latency.P99),
+			ns(res.Latency.P999),
+			ns(res.Latency.Max),
+			ns(res.Latency.StdDev),
+			strconv.Itoa(res.MessageSize),
+			strconv.Itoa(m.Parameters.Concurrency),
+			m.CreatedAt.Format(time.RFC3339),
+			m.Hostname,
+			m.GitCommit,
+			m.GoVersion,
+			strconv.Itoa(m.GOMAXPROCS),
+			strconv.FormatInt(res.BytesSent, 10),
+			strconv.FormatInt(res.BytesReceived, 10),
+			strconv.FormatFloat(res.BytesPerMessage, 'f', 1, 64),
+			strconv.FormatFloat(res.OverheadRatio, 'f', 4, 64),
+			strconv.FormatInt(res.WireReads, 10),
+			strconv.FormatInt(res.WireWrites, 10),
+			strconv.Itoa(res.Partial),
+			strconv.Itoa(res.Abandoned),
+			csvProtocolStats(res.ProtocolStats),
+			strconv.Itoa(SchemaVersion),
+			m.GOOS,
+			m.GOARCH,
+			strconv.Itoa(m.NumCPU),
+			strconv.Itoa(m.Parameters.MessageCount),
+			ns(m.Parameters.Duration),
+			strconv.Itoa(m.Parameters.WarmupCount),
+			ns(m.Parameters.WarmupDuration),
+			strconv.FormatFloat(m.Parameters.Rate, 'f', -1, 64),
+			ns(m.Parameters.Timeout),
+		}
+
+		var counts [model.NumErrorKinds]int
+		var samples []string
+		for _, b := range res.ErrorBreakdown {
+			if k, ok := model.ParseErrorKind(b.Kind); ok {
+				counts[k] = b.Count
+			}
+			for _, s := range b.Samples {
+				samples = append(samples, b.Kind+": "+s)
+			}
+		}
+		for _, n := range counts {
+			row = append(row, strconv.Itoa(n))
+		}
+		row = append(row, strings.Join(samples, csvSampleSep))
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvProtocolStats encodes counters and gauges as JSON in a single cell.
+// Traces are left to the JSON format, where they fit.
+func csvProtocolStats(stats *model.ProtocolStats) string {
+	if stats == nil {
+		return ""
+	}
+	data, err := json.Marshal(model.ProtocolStats{Counters: stats.Counters, Gauges: stats.Gauges})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func ns(d time.Duration) string {
+	return strconv.FormatInt(int64(d), 10)
+}
+
+// WriteTable renders the human-readable results table.
+func WriteTable(w io.Writer, r *Report) error {
+	if len(r.Codecs) > 0 {
+		return writeCodecTable(w, r.Codecs)
+	}
+
+	fmt.Fprintln(w, "\nResults:")
+	fmt.Fprintf(w, "%-14s %4s %7s %12s %10s %15s %10s %10s %10s %10s %10s %10s %11s %11s %11s %11s %11s %11s %11s %11s\n",
+		"Protocol", "Run", "Size", "Time", "Messages", "Msgs/sec", "Errors", "Timeouts", "Missing", "Dups", "OutOfOrder", "Corrupted",
+		"Min", "Mean", "P50", "P90", "P99", "P99.9", "Max", "StdDev")
+	fmt.Fprintln(w, strings.Repeat("-", 225))
+
+	for _, result := range r.Results {
+		_, err := fmt.Fprintf(w, "%-14s %4d %7s %12s %10d %15.2f %10d %10d %10d %10d %10d %10d %11s %11s %11s %11s %11s %11s %11s %11s\n",
+			result.Protocol,
+			result.Run,
+			fmt.Sprintf("%dKB", result.MessageSize),
+			result.TotalTime.Round(time.Millisecond),
+			result.Messages,
+			result.MessagesPerSecond,
+			result.Errors,
+			result.Timeouts,
+			result.Missing,
+			result.Duplicates,
+			result.OutOfOrder,
+			result.Corrupted,
+			result.Latency.Min.Round(time.Microsecond),
+			result.Latency.Mean.Round(time.Microsecond),
+			result.Latency.P50.Round(time.Microsecond),
+			result.Latency.P90.Round(time.Microsecond),
+			result.Latency.P99.Round(time.Microsecond),
+			result.Latency.P999.Round(time.Microsecond),
+			result.Latency.Max.Round(time.Microsecond),
+			result.Latency.StdDev.Round(time.Microsecond),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	writeTraffic(w, r.Results)
+	writeIncomplete(w, r.Results)
+	writeProtocolStats(w, r.Results)
+	writeErrors(w, r.Results)
+
+	if r.Sweep != nil {
+		WriteSweep(w, r.Sweep)
+	}
+	return nil
+}
+
+// writeTraffic lists the bytes and I/O calls counted on each server's
+// sockets. Results without a server ledger have none and are skipped.
+func writeTraffic(w io.Writer, results []benchmark.Result) {
+	header := false
+	for _, result := range results {
+		if result.BytesSent == 0 && result.BytesReceived == 0 {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(w, "\nWire traffic (counted at the server):")
+			fmt.Fprintf(w, "%-14s %4s %7s %14s %14s %12s %9s %10s %10s\n",
+				"Protocol", "Run", "Size", "Sent", "Received", "Bytes/msg", "Overhead", "Reads", "Writes")
+			fmt.Fprintln(w, strings.Repeat("-", 102))
+			header = true
+		}
+		fmt.Fprintf(w, "%-14s %4d %7s %14d %14d %12.0f %8.2fx %10d %10d\n",
+			result.Protocol,
+			result.Run,
+			fmt.Sprintf("%dKB", result.MessageSize),
+			result.BytesSent,
+			result.BytesReceived,
+			result.BytesPerMessage,
+			result.OverheadRatio,
+			result.WireReads,
+			result.WireWrites,
+		)
+	}
+}
+
+// writeIncomplete lists results whose server was left holding, or gave up
+// on, messages that arrived only in part.
+func writeIncomplete(w io.Writer, results []benchmark.Result) {
+	header := false
+	for _, result := range results {
+		if result.Partial == 0 && result.Abandoned == 0 {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(w, "\nIncomplete messages (counted at the server):")
+			fmt.Fprintf(w, "%-14s %4s %7s %10s %10s\n", "Protocol", "Run", "Size", "Partial", "Abandoned")
+			fmt.Fprintln(w, strings.Repeat("-", 49))
+			header = true
+		}
+		fmt.Fprintf(w, "%-14s %4d %7s %10d %10d\n",
+			result.Protocol, result.Run, fmt.Sprintf("%dKB", result.MessageSize), result.Partial, result.Abandoned)
+	}
+}
+
+// writeProtocolStats lists the protocol-specific statistics of each result
+// that has them, summarising traces by their range.
+func writeProtocolStats(w io.Writer, results []benchmark.Result) {
+	header := false
+	for _, result := range results {
+		stats := result.ProtocolStats
+		if stats == nil {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(w, "\nProtocol statistics:")
+			header = true
+		}
+
+		fmt.Fprintf(w, "%s run %d, %dKB:\n", result.Protocol, result.Run, result.MessageSize)
+		var parts []string
+		for _, name := range sortedKeys(stats.Counters) {
+			parts = append(parts, fmt.Sprintf("%s %d", name, stats.Counters[name]))
+		}
+		for _, name := range sortedKeys(stats.Gauges) {
+			parts = append(parts, fmt.Sprintf("%s %.1f", name, stats.Gauges[name]))
+		}
+		if len(parts) > 0 {
+			fmt.Fprintf(w, "  %s\n", strings.Join(parts, ", "))
+		}
+		for _, name := range sortedKeys(stats.Traces) {
+			trace := stats.Traces[name]
+			if len(trace) == 0 {
+				continue
+			}
+			lo, hi := trace[0].Value, trace[0].Value
+			for _, p := range trace {
+				lo, hi = min(lo, p.Value), max(hi, p.Value)
+			}
+			fmt.Fprintf(w, "  %s trace: %d points, %.1f to %.1f, ending at %.1f\n", name, len(trace), lo, hi, trace[len(trace)-1].Value)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeErrors lists what went wrong in each result that had failures.
+func writeErrors(w io.Writer, results []benchmark.Result) {
+	header := false
+	for _, result := range results {
+		if len(result.ErrorBreakdown) == 0 {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(w, "\nErrors:")
+			header = true
+		}
+
+		fmt.Fprintf(w, "%s run %d, %dKB:\n", result.Protocol, result.Run, result.MessageSize)
+		for _, b := range result.ErrorBreakdown {
+			fmt.Fprintf(w, "  %-9s %6d", b.Kind, b.Count)
+			for i, s := range b.Samples {
+				if i > 0 {
+					fmt.Fprintf(w, "\n  %16s", "")
+				}
+				fmt.Fprintf(w, "  %s", s)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"protobench/internal/model"
+	"protobench/internal/wire"
+)
+
+// HTTP posts each frame as the body of an HTTP/1.1 request and maps the
+// Status onto the response code.
+type HTTP struct{}
+
+func (HTTP) Name() string { return "http" }
+
+var statusCodes = map[Status]int{
+	StatusOK:            http.StatusOK,
+	StatusDecodeError:   http.StatusBadRequest,
+	StatusChecksumError: http.StatusUnprocessableEntity,
+}
+
+func (HTTP) Listen(addr string, counter *wire.Counter, handler Handler) (Listener, error) {
+	listener, err := wire.Listen("tcp", addr, counter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /frame", func(w http.ResponseWriter, r *http.Request) {
+		frame, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		code, ok := statusCodes[handler(frame)]
+		if !ok {
+			code = http.StatusForbidden
+		}
+		w.WriteHeader(code)
+	})
+
+	l := &httpListener{listener: listener, server: &http.Server{Handler: mux}}
+	go l.server.Serve(listener)
+	return l, nil
+}
+
+func (HTTP) Dial(addr string) Conn {
+	return &httpConn{
+		url: "http://" + addr + "/frame",
+		client: &http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		},
+	}
+}
+
+type httpListener struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+func (l *httpListener) Addr() string {
+	return model.DialAddr(l.listener.Addr())
+}
+
+func (l *httpListener) Close() error {
+	return l.server.Close()
+}
+
+type httpConn struct {
+	url    string
+	client *http.Client
+}
+
+func (c *httpConn) RoundTrip(ctx context.Context, frame []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(frame))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return model.Errorf(model.NetErrorKind(err, model.ErrWrite), "failed to send frame: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	for status, code := range statusCodes {
+		if resp.StatusCode == code {
+			return status.Err()
+		}
+	}
+	return model.Errorf(model.ErrRejected, "unexpected status code: %d", resp.StatusCode)
+}
+
+func (c *httpConn) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,157 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"protobench/internal/model"
+	"protobench/internal/wire"
+)
+
+// TCP frames are a 4-byte big-endian length followed by the payload, each
+// answered with a one-byte Status on the same connection.
+type TCP struct{}
+
+// maxFrameSize bounds the length a prefix may claim, so that a bad or
+// hostile peer cannot make the server allocate without limit.
+const maxFrameSize = 64 << 20
+
+func (TCP) Name() string { return "tcp" }
+
+func (TCP) Listen(addr string, counter *wire.Counter, handler Handler) (Listener, error) {
+	listener, err := wire.Listen("tcp", addr, counter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	l := &tcpListener{listener: listener, handler: handler}
+	go l.acceptLoop()
+	return l, nil
+}
+
+func (TCP) Dial(addr string) Conn {
+	return &tcpConn{addr: addr}
+}
+
+type tcpListener struct {
+	listener net.Listener
+	handler  Handler
+}
+
+func (l *tcpListener) Addr() string {
+	return model.DialAddr(l.listener.Addr())
+}
+
+func (l *tcpListener) Close() error {
+	return l.listener.Close()
+}
+
+func (l *tcpListener) acceptLoop() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		go l.serve(conn)
+	}
+}
+
+func (l *tcpListener) serve(conn net.Conn) {
+	defer conn.Close()
+
+	// Buffer the prefix and payload reads, and reuse one frame buffer for
+	// the life of the connection
+	r := bufio.NewReaderSize(conn, 64*1024)
+	var size [4]byte
+	var frame []byte
+	for {
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		n := int(binary.BigEndian.Uint32(size[:]))
+		if n > maxFrameSize {
+			// The stream cannot be resynchronised past a frame not read
+			return
+		}
+		if cap(frame) < n {
+			frame = make([]byte, n)
+		}
+		frame = frame[:n]
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte{byte(l.handler(frame))}); err != nil {
+			return
+		}
+	}
+}
+
+type tcpConn struct {
+	mu   sync.Mutex
+	addr string
+	conn net.Conn
+	buf  []byte // reused for every frame
+}
+
+func (c *tcpConn) RoundTrip(ctx context.Context, frame []byte) error {
+	if len(frame) > maxFrameSize {
+		return model.Errorf(model.ErrRejected, "frame of %d bytes exceeds the %d byte limit", len(frame), maxFrameSize)
+	}
+
+	// Frames on the shared stream must not interleave
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", c.addr)
+		if err != nil {
+			return model.Errorf(model.ErrDial, "failed to connect: %w", err)
+		}
+		c.conn = conn
+	}
+
+	// A zero deadline clears any left over from a previous frame
+	deadline, _ := ctx.Deadline()
+	c.conn.SetDeadline(deadline)
+
+	status, err := c.exchange(frame)
+	if err != nil {
+		// The stream may be left mid-frame, so start over on a new one
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	return status.Err()
+}
+
+func (c *tcpConn) exchange(frame []byte) (Status, error) {
+	// Prefix and payload go out in a single write
+	c.buf = binary.BigEndian.AppendUint32(c.buf[:0], uint32(len(frame)))
+	c.buf = append(c.buf, frame...)
+	if _, err := c.conn.Write(c.buf); err != nil {
+		return 0, model.Errorf(model.ErrWrite, "failed to send frame: %w", err)
+	}
+
+	var status [1]byte
+	if _, err := io.ReadFull(c.conn, status[:]); err != nil {
+		return 0, model.Errorf(model.ErrRead, "failed to read status: %w", err)
+	}
+	return Status(status[0]), nil
+}
+
+func (c *tcpConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,77 @@
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"protobench/internal/model"
+	"protobench/internal/wire"
+)
+
+func TestTCPDropsOversizedFrames(t *testing.T) {
+	var counter wire.Counter
+	handled := make(chan struct{}, 1)
+	listener, err := TCP{}.Listen("127.0.0.1:0", &counter, func([]byte) Status {
+		handled <- struct{}{}
+		return StatusOK
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	prefix := binary.BigEndian.AppendUint32(nil, maxFrameSize+1)
+	if _, err := conn.Write(prefix); err != nil {
+		t.Fatal(err)
+	}
+	var status [1]byte
+	if _, err := io.ReadFull(conn, status[:]); !errors.Is(err, io.EOF) {
+		t.Fatalf("read after oversized prefix: %v, want EOF", err)
+	}
+	select {
+	case <-handled:
+		t.Error("oversized frame reached the handler")
+	default:
+	}
+}
+
+func TestTCPRoundTrip(t *testing.T) {
+	var counter wire.Counter
+	listener, err := TCP{}.Listen("127.0.0.1:0", &counter, func(frame []byte) Status {
+		if string(frame) != "frame" {
+			return StatusDecodeError
+		}
+		return StatusOK
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	conn := TCP{}.Dial(listener.Addr())
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := conn.RoundTrip(ctx, []byte("frame")); err != nil {
+		t.Errorf("valid frame: %v", err)
+	}
+	if err := conn.RoundTrip(ctx, []byte("other")); model.Classify(err) != model.ErrDecode {
+		t.Errorf("undecodable frame: %v, want a decode error", err)
+	}
+	if err := conn.RoundTrip(ctx, make([]byte, maxFrameSize+1)); model.Classify(err) != model.ErrRejected {
+		t.Errorf("oversized frame: %v, want a rejected error", err)
+	}
+}
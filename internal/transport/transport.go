@@ -0,0 +1,78 @@
+// Package transport moves opaque frames between a client and a server, each
+// answered with a Status, independently of how the frames are encoded.
+package transport
+
+import (
+	"context"
+	"strings"
+
+	"protobench/internal/model"
+	"protobench/internal/wire"
+)
+
+// Status is the server's verdict on one frame.
+type Status byte
+
+const (
+	StatusOK Status = iota
+	StatusDecodeError
+	StatusChecksumError
+)
+
+// Err converts a non-OK status into a classified send error.
+func (s Status) Err() error {
+	switch s {
+	case StatusOK:
+		return nil
+	case StatusDecodeError:
+		return model.Errorf(model.ErrDecode, "server could not decode frame")
+	case StatusChecksumError:
+		return model.Errorf(model.ErrChecksum, "server found checksum mismatch")
+	default:
+		return model.Errorf(model.ErrRejected, "server rejected frame with status %d", s)
+	}
+}
+
+// Handler processes one received frame. The frame is only valid until the
+// handler returns, since transports may reuse its buffer.
+type Handler func(frame []byte) Status
+
+// Transport creates both ends of a frame channel.
+type Transport interface {
+	Name() string
+	// Listen starts serving handler on addr and returns once clients can
+	// connect. Traffic on the server's sockets counts into counter.
+	Listen(addr string, counter *wire.Counter, handler Handler) (Listener, error)
+	// Dial returns a connection to addr. Connecting may be deferred to the
+	// first frame.
+	Dial(addr string) Conn
+}
+
+type Listener interface {
+	// Addr returns the address clients should dial.
+	Addr() string
+	Close() error
+}
+
+// Conn sends frames and waits for each one's status.
+type Conn interface {
+	RoundTrip(ctx context.Context, frame []byte) error
+	Close() error
+}
+
+var transports = []Transport{TCP{}, HTTP{}}
+
+// All returns every transport.
+func All() []Transport {
+	return append([]Transport(nil), transports...)
+}
+
+// Lookup finds a transport by case-insensitive name.
+func Lookup(name string) (Transport, bool) {
+	for _, t := range transports {
+		if strings.EqualFold(t.Name(), name) {
+			return t, true
+		}
+	}
+	return nil, false
+}
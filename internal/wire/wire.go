@@ -0,0 +1,136 @@
+// Package wire counts the bytes and I/O calls that pass through a server's
+// sockets, so that the cost of a protocol's framing, headers and
+// acknowledgements can be measured alongside its payload.
+package wire
+
+import (
+	"net"
+	"sync/atomic"
+)
+
+// Stats is the traffic seen by one server. Reads and Writes count I/O calls
+// on its connections, which for datagram sockets is one per packet.
+type Stats struct {
+	BytesRead    int64 `json:"bytes_read"`
+	BytesWritten int64 `json:"bytes_written"`
+	Reads        int64 `json:"reads"`
+	Writes       int64 `json:"writes"`
+}
+
+// Counter accumulates Stats. It is safe for concurrent use.
+type Counter struct {
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+	reads        atomic.Int64
+	writes       atomic.Int64
+}
+
+func (c *Counter) read(n int) {
+	c.reads.Add(1)
+	c.bytesRead.Add(int64(n))
+}
+
+func (c *Counter) write(n int) {
+	c.writes.Add(1)
+	c.bytesWritten.Add(int64(n))
+}
+
+func (c *Counter) Stats() Stats {
+	return Stats{
+		BytesRead:    c.bytesRead.Load(),
+		BytesWritten: c.bytesWritten.Load(),
+		Reads:        c.reads.Load(),
+		Writes:       c.writes.Load(),
+	}
+}
+
+// Reset zeroes the counts. Connections keep counting into c afterwards.
+func (c *Counter) Reset() {
+	c.bytesRead.Store(0)
+	c.bytesWritten.Store(0)
+	c.reads.Store(0)
+	c.writes.Store(0)
+}
+
+// Listen is net.Listen with every accepted connection counted into c.
+func Listen(network, addr string, c *Counter) (net.Listener, error) {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewListener(l, c), nil
+}
+
+// ListenPacket is net.ListenPacket with the socket counted into c.
+func ListenPacket(network, addr string, c *Counter) (net.PacketConn, error) {
+	conn, err := net.ListenPacket(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &packetConn{PacketConn: conn, counter: c}, nil
+}
+
+// NewListener wraps l so that every connection it accepts counts into c.
+func NewListener(l net.Listener, c *Counter) net.Listener {
+	return &listener{Listener: l, counter: c}
+}
+
+type listener struct {
+	net.Listener
+	counter *Counter
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn, l.counter), nil
+}
+
+// NewConn wraps conn so that its traffic counts into c.
+func NewConn(conn net.Conn, c *Counter) net.Conn {
+	return &countingConn{Conn: conn, counter: c}
+}
+
+type countingConn struct {
+	net.Conn
+	counter *Counter
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.counter.read(n)
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.counter.write(n)
+	}
+	return n, err
+}
+
+type packetConn struct {
+	net.PacketConn
+	counter *Counter
+}
+
+func (c *packetConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	if err == nil {
+		c.counter.read(n)
+	}
+	return n, addr, err
+}
+
+func (c *packetConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(b, addr)
+	if err == nil {
+		c.counter.write(n)
+	}
+	return n, err
+}